@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"a2a/models"
+)
+
+// AgentCardPath is where an agent publishes its card, relative to its base URL
+const AgentCardPath = "/.well-known/agent.json"
+
+// ErrInvalidCard is wrapped by the errors ValidateCard returns
+var ErrInvalidCard = errors.New("invalid agent card")
+
+// ResolveCard fetches the card an agent publishes under baseURL and validates it. The card's URL
+// is the endpoint to pass to NewClient. WithHTTPClient and WithTimeout apply to the fetch.
+func ResolveCard(ctx context.Context, baseURL string, opts ...Option) (*models.AgentCard, error) {
+	c := NewClient(baseURL, opts...)
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	cardURL := strings.TrimSuffix(baseURL, "/") + AgentCardPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cardURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create agent card request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch agent card: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read agent card: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var card models.AgentCard
+	if err := json.Unmarshal(body, &card); err != nil {
+		return nil, fmt.Errorf("decode agent card: %w", err)
+	}
+	if err := ValidateCard(&card); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// ValidateCard checks that a card has the fields a client needs before talking to the agent:
+// a name, a version, an absolute http(s) endpoint URL and uniquely identified skills
+func ValidateCard(card *models.AgentCard) error {
+	if strings.TrimSpace(card.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCard)
+	}
+	if strings.TrimSpace(card.Version) == "" {
+		return fmt.Errorf("%w: version is required", ErrInvalidCard)
+	}
+	endpoint, err := url.Parse(card.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("%w: url %q is not an absolute http(s) URL", ErrInvalidCard, card.URL)
+	}
+
+	seen := make(map[string]bool, len(card.Skills))
+	for i, skill := range card.Skills {
+		if skill.ID == "" {
+			return fmt.Errorf("%w: skill %d has no id", ErrInvalidCard, i)
+		}
+		if seen[skill.ID] {
+			return fmt.Errorf("%w: duplicate skill id %q", ErrInvalidCard, skill.ID)
+		}
+		seen[skill.ID] = true
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"a2a/models"
+)
+
+// defaultTimeout bounds a non-streaming call when the caller's context has no deadline
+const defaultTimeout = 60 * time.Second
+
+// Client talks to a single A2A agent endpoint
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+	// timeout bounds non-streaming calls whose context has no deadline; zero means no limit
+	timeout time.Duration
+	// header is added to every JSON-RPC request, carrying credentials among other things
+	header http.Header
+	nextID atomic.Int64
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient replaces the HTTP client used for requests. Streams stay open for as long as
+// the agent runs the task, so the client should not set an overall Timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout bounds non-streaming calls whose context has no deadline. Zero disables the limit.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithHeader adds a header to every JSON-RPC request
+func WithHeader(name, value string) Option {
+	return func(c *Client) {
+		c.header.Add(name, value)
+	}
+}
+
+// WithBearerToken authenticates requests with an "Authorization: Bearer" header, for agents
+// whose card lists the bearer scheme
+func WithBearerToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithAPIKey authenticates requests with an API key sent in header, for agents whose card
+// lists the apiKey scheme. Agents read X-API-Key unless they say otherwise.
+func WithAPIKey(header, key string) Option {
+	return WithHeader(header, key)
+}
+
+// WithBasicAuth authenticates requests with HTTP basic credentials, for agents whose card
+// lists the basic scheme
+func WithBasicAuth(username, password string) Option {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return WithHeader("Authorization", "Basic "+credentials)
+}
+
+// NewClient creates a client for the agent served at endpoint
+func NewClient(endpoint string, opts ...Option) *Client {
+	c := &Client{
+		endpoint:   endpoint,
+		httpClient: &http.Client{},
+		timeout:    defaultTimeout,
+		header:     make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SendMessage sends a message to a task with message/send and returns the task as the agent
+// left it
+func (c *Client) SendMessage(ctx context.Context, params models.TaskSendParams) (*models.Task, error) {
+	var task models.Task
+	if err := c.call(ctx, "message/send", params, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetTask fetches a task with tasks/get
+func (c *Client) GetTask(ctx context.Context, params models.TaskQueryParams) (*models.Task, error) {
+	var task models.Task
+	if err := c.call(ctx, "tasks/get", params, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// CancelTask cancels a task with tasks/cancel
+func (c *Client) CancelTask(ctx context.Context, params models.TaskIDParams) (*models.Task, error) {
+	var task models.Task
+	if err := c.call(ctx, "tasks/cancel", params, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// SetTaskPushNotification registers a webhook for a task's events
+func (c *Client) SetTaskPushNotification(ctx context.Context, config models.TaskPushNotificationConfig) (*models.TaskPushNotificationConfig, error) {
+	var result models.TaskPushNotificationConfig
+	if err := c.call(ctx, "tasks/pushNotification/set", config, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetTaskPushNotification reads back the webhook registered for a task
+func (c *Client) GetTaskPushNotification(ctx context.Context, params models.TaskIDParams) (*models.TaskPushNotificationConfig, error) {
+	var result models.TaskPushNotificationConfig
+	if err := c.call(ctx, "tasks/pushNotification/get", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StreamMessage sends a message with message/stream and returns the stream of events the
+// agent produces while working on it. The caller must close the stream.
+func (c *Client) StreamMessage(ctx context.Context, params models.TaskSendParams) (*Stream, error) {
+	return c.stream(ctx, "message/stream", params, "")
+}
+
+// Resubscribe reattaches to the event stream of a task's latest run with tasks/resubscribe.
+// A non-empty lastEventID, usually Stream.LastEventID of an earlier stream, skips the events
+// that were already received. The caller must close the stream.
+func (c *Client) Resubscribe(ctx context.Context, params models.TaskQueryParams, lastEventID string) (*Stream, error) {
+	return c.stream(ctx, "tasks/resubscribe", params, lastEventID)
+}
+
+// newRequest builds the HTTP request for a JSON-RPC call
+func (c *Client) newRequest(ctx context.Context, method string, params any) (*http.Request, error) {
+	body, err := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+				ID: strconv.FormatInt(c.nextID.Add(1), 10),
+			},
+		},
+		Method: method,
+		Params: params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode %s request: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create %s request: %w", method, err)
+	}
+	for name, values := range c.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// call performs a non-streaming JSON-RPC call and decodes its result into result
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := c.newRequest(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	return decodeResponse(method, resp, result)
+}
+
+// stream opens a streaming JSON-RPC call. An agent that refuses the call answers with a plain
+// JSON-RPC error, which is returned instead of a stream.
+func (c *Client) stream(ctx context.Context, method string, params any, lastEventID string) (*Stream, error) {
+	req, err := c.newRequest(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if resp.StatusCode == http.StatusOK && isEventStream(resp.Header.Get("Content-Type")) {
+		return newStream(resp.Body), nil
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+	if err := decodeResponse(method, resp, nil); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s: agent did not answer with an event stream", method)
+}
+
+// rpcResponse is a JSON-RPC response whose result is decoded later
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// decodeResponse reads a JSON-RPC response, returning the agent's error if there is one and
+// otherwise decoding the result into result (when it is not nil)
+func decodeResponse(method string, resp *http.Response, result any) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: read response: %w", method, err)
+	}
+
+	var rpcResp rpcResponse
+	if err := json.Unmarshal(body, &rpcResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+		}
+		return fmt.Errorf("%s: decode response: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("%s: decode result: %w", method, err)
+	}
+	return nil
+}
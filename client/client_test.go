@@ -0,0 +1,388 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"a2a/models"
+	"a2a/server"
+)
+
+var testCard = models.AgentCard{
+	Name:    "Test Agent",
+	URL:     "http://localhost",
+	Version: "1.0.0",
+	Capabilities: models.AgentCapabilities{
+		Streaming:         models.BoolPtr(true),
+		PushNotifications: models.BoolPtr(true),
+	},
+}
+
+// echoHandler streams the message text back as an artifact and asks for more input
+func echoHandler(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+	update(models.TaskArtifactUpdateEvent{
+		ID:       task.ID,
+		Artifact: models.Artifact{Parts: message.Parts},
+	})
+	task.Status.State = models.TaskStateInputRequired
+	task.Status.Message = &models.Message{Role: "agent", Parts: message.Parts}
+	return task, nil
+}
+
+func newTestClient(t *testing.T, handler server.TaskHandler, opts ...server.Option) *Client {
+	t.Helper()
+	httpServer := httptest.NewServer(server.NewA2AServer(testCard, handler, opts...))
+	t.Cleanup(httpServer.Close)
+	return NewClient(httpServer.URL)
+}
+
+func textMessage(text string) models.Message {
+	return models.Message{Role: "user", Parts: []models.Part{{Text: models.StringPtr(text)}}}
+}
+
+func TestClient_SendGetCancel(t *testing.T) {
+	c := newTestClient(t, echoHandler)
+	ctx := context.Background()
+
+	task, err := c.SendMessage(ctx, models.TaskSendParams{ID: "task-1", Message: textMessage("hello")})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if task.Status.State != models.TaskStateInputRequired || *task.Status.Message.Parts[0].Text != "hello" {
+		t.Errorf("Expected the echoed reply, got %+v", task.Status)
+	}
+
+	task, err = c.GetTask(ctx, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "task-1"}})
+	if err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	}
+	if len(task.History) != 2 {
+		t.Errorf("Expected user and agent messages in history, got %d", len(task.History))
+	}
+
+	task, err = c.CancelTask(ctx, models.TaskIDParams{ID: "task-1"})
+	if err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+	if task.Status.State != models.TaskStateCanceled {
+		t.Errorf("Expected canceled task, got %s", task.Status.State)
+	}
+}
+
+func TestClient_RPCError(t *testing.T) {
+	c := newTestClient(t, echoHandler)
+
+	_, err := c.GetTask(context.Background(), models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}})
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != models.ErrorCodeTaskNotFound {
+		t.Fatalf("Expected task not found error, got %v", err)
+	}
+}
+
+func TestClient_HTTPError(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer httpServer.Close()
+
+	_, err := NewClient(httpServer.URL).GetTask(context.Background(), models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "task-1"}})
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected HTTP 503 error, got %v", err)
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	httpServer := httptest.NewServer(server.NewA2AServer(testCard, handler))
+	defer httpServer.Close()
+
+	c := NewClient(httpServer.URL, WithTimeout(50*time.Millisecond))
+	_, err := c.SendMessage(context.Background(), models.TaskSendParams{ID: "task-1", Message: textMessage("hello")})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestClient_StreamAndResubscribe(t *testing.T) {
+	c := newTestClient(t, echoHandler)
+	ctx := context.Background()
+
+	stream, err := c.StreamMessage(ctx, models.TaskSendParams{ID: "task-1", Message: textMessage("hello")})
+	if err != nil {
+		t.Fatalf("StreamMessage failed: %v", err)
+	}
+	var events []*Event
+	for event, err := range stream.Events() {
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		events = append(events, event)
+	}
+	_ = stream.Close()
+
+	if len(events) != 3 {
+		t.Fatalf("Expected working, artifact and final events, got %d", len(events))
+	}
+	if events[0].Status == nil || events[0].Status.Status.State != models.TaskStateWorking {
+		t.Errorf("Expected working status first, got %+v", events[0])
+	}
+	if events[1].Artifact == nil || *events[1].Artifact.Artifact.Parts[0].Text != "hello" {
+		t.Errorf("Expected the echoed artifact, got %+v", events[1])
+	}
+	if !events[2].Final() || events[2].Status.Status.State != models.TaskStateInputRequired {
+		t.Errorf("Expected final input-required status, got %+v", events[2])
+	}
+	if stream.LastEventID() != "3" {
+		t.Errorf("Expected last event ID 3, got %q", stream.LastEventID())
+	}
+
+	// Resuming after the first event only replays the rest
+	stream, err = c.Resubscribe(ctx, models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "task-1"}}, "1")
+	if err != nil {
+		t.Fatalf("Resubscribe failed: %v", err)
+	}
+	defer func() { _ = stream.Close() }()
+	first, err := stream.Recv()
+	if err != nil || first.ID != "2" || first.Artifact == nil {
+		t.Fatalf("Expected artifact event 2, got %+v (%v)", first, err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Expected final event, got %v", err)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected end of stream, got %v", err)
+	}
+}
+
+func TestClient_StreamErrors(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		return nil, server.NewError(models.ErrorCodeUnsupportedOperation, "not today")
+	}
+	c := newTestClient(t, handler)
+	ctx := context.Background()
+
+	stream, err := c.StreamMessage(ctx, models.TaskSendParams{ID: "task-1", Message: textMessage("hello")})
+	if err != nil {
+		t.Fatalf("StreamMessage failed: %v", err)
+	}
+	defer func() { _ = stream.Close() }()
+
+	var sawError, sawFinal bool
+	for event, err := range stream.Events() {
+		var rpcErr *RPCError
+		switch {
+		case errors.As(err, &rpcErr):
+			sawError = rpcErr.Code == models.ErrorCodeUnsupportedOperation
+		case err != nil:
+			t.Fatalf("Stream failed: %v", err)
+		case event.Final():
+			sawFinal = event.Status.Status.State == models.TaskStateFailed
+		}
+	}
+	if !sawError || !sawFinal {
+		t.Errorf("Expected an error event and a final failed status, got error %v final %v", sawError, sawFinal)
+	}
+
+	// A stream the agent refuses up front comes back as an error, not a stream
+	_, err = c.StreamMessage(ctx, models.TaskSendParams{ID: "task-1", Message: textMessage("again")})
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != models.ErrorCodeUnsupportedOperation {
+		t.Fatalf("Expected unsupported operation error, got %v", err)
+	}
+}
+
+func TestClient_PushNotificationConfig(t *testing.T) {
+	c := newTestClient(t, echoHandler, server.WithPushDelivery(server.DeliveryPolicy{AllowPrivateAddresses: true}))
+	ctx := context.Background()
+	if _, err := c.SendMessage(ctx, models.TaskSendParams{ID: "task-1", Message: textMessage("hello")}); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	config := models.TaskPushNotificationConfig{
+		ID:                     "task-1",
+		PushNotificationConfig: models.PushNotificationConfig{URL: "http://127.0.0.1:9/hook"},
+	}
+	if _, err := c.SetTaskPushNotification(ctx, config); err != nil {
+		t.Fatalf("SetTaskPushNotification failed: %v", err)
+	}
+	got, err := c.GetTaskPushNotification(ctx, models.TaskIDParams{ID: "task-1"})
+	if err != nil {
+		t.Fatalf("GetTaskPushNotification failed: %v", err)
+	}
+	if got.PushNotificationConfig.URL != config.PushNotificationConfig.URL {
+		t.Errorf("Expected URL %s, got %s", config.PushNotificationConfig.URL, got.PushNotificationConfig.URL)
+	}
+}
+
+func TestResolveCard(t *testing.T) {
+	mux := http.NewServeMux()
+	server.NewA2AServer(testCard, echoHandler).Mount(mux, "/agent")
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	card, err := ResolveCard(context.Background(), httpServer.URL+"/agent/")
+	if err != nil {
+		t.Fatalf("ResolveCard failed: %v", err)
+	}
+	if card.Name != testCard.Name || card.URL != testCard.URL {
+		t.Errorf("Expected the published card, got %+v", card)
+	}
+
+	_, err = ResolveCard(context.Background(), httpServer.URL+"/missing")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected HTTP 404 error, got %v", err)
+	}
+}
+
+func TestValidateCard(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(card *models.AgentCard)
+		valid  bool
+	}{
+		{"valid", func(card *models.AgentCard) {}, true},
+		{"missing name", func(card *models.AgentCard) { card.Name = " " }, false},
+		{"missing version", func(card *models.AgentCard) { card.Version = "" }, false},
+		{"relative url", func(card *models.AgentCard) { card.URL = "/agent" }, false},
+		{"unsupported scheme", func(card *models.AgentCard) { card.URL = "ftp://localhost/agent" }, false},
+		{"skill without id", func(card *models.AgentCard) { card.Skills = []models.AgentSkill{{Name: "x"}} }, false},
+		{"duplicate skill", func(card *models.AgentCard) {
+			card.Skills = []models.AgentSkill{{ID: "a", Name: "x"}, {ID: "a", Name: "y"}}
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := testCard
+			tt.modify(&card)
+			err := ValidateCard(&card)
+			if tt.valid && err != nil {
+				t.Errorf("Expected a valid card, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidCard) {
+				t.Errorf("Expected ErrInvalidCard, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_Credentials(t *testing.T) {
+	card := testCard
+	card.Authentication = &models.AgentAuthentication{Schemes: []string{server.SchemeBearer, server.SchemeBasic}}
+	httpServer := httptest.NewServer(server.NewA2AServer(card, echoHandler, server.WithCardAuth(server.AuthValidators{
+		Bearer: server.StaticTokens(map[string]string{"secret": "agent-a"}),
+		Basic:  server.StaticCredentials(map[string]string{"alice": "wonderland"}),
+	})))
+	defer httpServer.Close()
+	ctx := context.Background()
+	params := models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}}
+
+	_, err := NewClient(httpServer.URL).GetTask(ctx, params)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "Unauthorized" {
+		t.Fatalf("Expected an unauthorized error, got %v", err)
+	}
+
+	for _, opt := range []Option{WithBearerToken("secret"), WithBasicAuth("alice", "wonderland")} {
+		_, err = NewClient(httpServer.URL, opt).GetTask(ctx, params)
+		if !errors.As(err, &rpcErr) || rpcErr.Code != models.ErrorCodeTaskNotFound {
+			t.Errorf("Expected the call to get through to task not found, got %v", err)
+		}
+	}
+}
+
+func TestNotificationVerifier(t *testing.T) {
+	type notification struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan notification, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- notification{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	agent := httptest.NewServer(server.NewA2AServer(testCard, echoHandler, server.WithPushDelivery(server.DeliveryPolicy{AllowPrivateAddresses: true})))
+	defer agent.Close()
+	_, err := NewClient(agent.URL).SendMessage(context.Background(), models.TaskSendParams{
+		ID:               "task-1",
+		Message:          textMessage("hello"),
+		PushNotification: &models.PushNotificationConfig{URL: receiver.URL, Token: models.StringPtr("secret")},
+	})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	var got notification
+	select {
+	case got = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a push notification")
+	}
+	request := func(body []byte) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(body))
+		r.Header = got.header.Clone()
+		return r
+	}
+
+	verifier, err := NewNotificationVerifier(agent.URL+JWKSPath, WithNotificationToken("secret"), WithNotificationAudience(receiver.URL))
+	if err != nil {
+		t.Fatalf("NewNotificationVerifier failed: %v", err)
+	}
+	body, err := verifier.Verify(request(got.body))
+	if err != nil {
+		t.Fatalf("Expected the notification to verify, got %v", err)
+	}
+	if !bytes.Equal(body, got.body) {
+		t.Errorf("Expected the notification body back, got %s", body)
+	}
+	if _, err := verifier.Verify(request(got.body)); !errors.Is(err, ErrInvalidNotification) {
+		t.Errorf("Expected a replayed notification to be refused, got %v", err)
+	}
+
+	// A fresh verifier has not seen the notification, so only the checks under test can fail it
+	checks := []struct {
+		name   string
+		opts   []VerifierOption
+		modify func(r *http.Request)
+		body   []byte
+	}{
+		{"tampered body", nil, nil, bytes.Replace(got.body, []byte("task-1"), []byte("task-2"), 1)},
+		{"missing signature", nil, func(r *http.Request) { r.Header.Del(NotificationSignatureHeader) }, got.body},
+		{"wrong token", []VerifierOption{WithNotificationToken("other")}, nil, got.body},
+		{"wrong audience", []VerifierOption{WithNotificationAudience("http://example.com/hook")}, nil, got.body},
+	}
+	for _, check := range checks {
+		t.Run(check.name, func(t *testing.T) {
+			verifier, err := NewNotificationVerifier(agent.URL+JWKSPath, check.opts...)
+			if err != nil {
+				t.Fatalf("NewNotificationVerifier failed: %v", err)
+			}
+			r := request(check.body)
+			if check.modify != nil {
+				check.modify(r)
+			}
+			if _, err := verifier.Verify(r); !errors.Is(err, ErrInvalidNotification) {
+				t.Errorf("Expected the notification to be refused, got %v", err)
+			}
+		})
+	}
+
+	// Notifications older than the maximum age are refused
+	stale, _ := NewNotificationVerifier(agent.URL+JWKSPath, WithMaxNotificationAge(time.Minute))
+	stale.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := stale.Verify(request(got.body)); !errors.Is(err, ErrInvalidNotification) {
+		t.Errorf("Expected a stale notification to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"a2a/models"
+)
+
+// RPCError is a JSON-RPC error returned by the agent. Use errors.As to inspect the code:
+//
+//	var rpcErr *client.RPCError
+//	if errors.As(err, &rpcErr) && rpcErr.Code == models.ErrorCodeTaskNotFound { ... }
+type RPCError struct {
+	Code    models.ErrorCode `json:"code"`
+	Message string           `json:"message"`
+	// Data is the optional detail the agent attached to the error, as raw JSON
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("a2a error %d: %s", e.Code, e.Message)
+}
+
+// HTTPError reports an HTTP response that did not carry a JSON-RPC answer
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d: %s", e.StatusCode, e.Body)
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"a2a/internal/jose"
+)
+
+const (
+	// JWKSPath is where an agent publishes the keys it signs push notifications with, relative
+	// to its endpoint
+	JWKSPath = "/.well-known/jwks.json"
+	// NotificationSignatureHeader carries the JWT that signs a push notification
+	NotificationSignatureHeader = "X-A2A-Notification-Signature"
+	// NotificationTokenHeader carries the token the receiver set in its push notification config
+	NotificationTokenHeader = "X-A2A-Notification-Token"
+)
+
+const (
+	// defaultNotificationMaxAge is how old a notification may be when it arrives
+	defaultNotificationMaxAge = 5 * time.Minute
+	// notificationLeeway tolerates clock skew between the agent and the receiver
+	notificationLeeway = 30 * time.Second
+	// maxNotificationSize caps the body the verifier reads
+	maxNotificationSize = 10 << 20
+)
+
+// ErrInvalidNotification is wrapped by the errors NotificationVerifier.Verify returns
+var ErrInvalidNotification = errors.New("invalid push notification")
+
+// NotificationVerifier checks the push notifications an agent sends to a receiver: the
+// signature against the agent's JWKS, the body digest, the notification's age and, to refuse
+// replays, that its ID has not been seen before. It is safe for concurrent use.
+type NotificationVerifier struct {
+	keys     jose.KeySet
+	token    string
+	audience string
+	maxAge   time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// VerifierOption configures a NotificationVerifier
+type VerifierOption func(*NotificationVerifier)
+
+// WithNotificationToken also requires the token the receiver registered with the push config
+func WithNotificationToken(token string) VerifierOption {
+	return func(v *NotificationVerifier) {
+		v.token = token
+	}
+}
+
+// WithNotificationAudience requires the signature to be addressed to url, the receiver's
+// push notification URL. Without it the audience is not checked.
+func WithNotificationAudience(url string) VerifierOption {
+	return func(v *NotificationVerifier) {
+		v.audience = url
+	}
+}
+
+// WithMaxNotificationAge sets how old a notification may be when it arrives (default 5m).
+// Notification IDs are remembered for as long, which is what refuses replays.
+func WithMaxNotificationAge(age time.Duration) VerifierOption {
+	return func(v *NotificationVerifier) {
+		if age > 0 {
+			v.maxAge = age
+		}
+	}
+}
+
+// NewNotificationVerifier creates a verifier trusting the keys at jwksURL, normally the agent's
+// endpoint + JWKSPath; a file path works too. The keys are fetched when first needed and again
+// when the agent starts signing with a new key.
+func NewNotificationVerifier(jwksURL string, opts ...VerifierOption) (*NotificationVerifier, error) {
+	keys, err := jose.LoadKeySet(jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	v := &NotificationVerifier{
+		keys:   keys,
+		maxAge: defaultNotificationMaxAge,
+		now:    time.Now,
+		seen:   make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v, nil
+}
+
+// notificationClaims are the claims of a notification signature
+type notificationClaims struct {
+	jose.Claims
+	TaskID     string `json:"taskId"`
+	BodySHA256 string `json:"request_body_sha256"`
+}
+
+// Verify checks an incoming notification request and returns its body. The request body is
+// consumed; it is replaced with a copy so r can still be read afterwards.
+func (v *NotificationVerifier) Verify(r *http.Request) ([]byte, error) {
+	if v.token != "" {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(NotificationTokenHeader)), []byte(v.token)) != 1 {
+			return nil, fmt.Errorf("%w: token mismatch", ErrInvalidNotification)
+		}
+	}
+	signature := r.Header.Get(NotificationSignatureHeader)
+	if signature == "" {
+		return nil, fmt.Errorf("%w: missing %s header", ErrInvalidNotification, NotificationSignatureHeader)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxNotificationSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read notification: %w", err)
+	}
+	if len(body) > maxNotificationSize {
+		return nil, fmt.Errorf("%w: body too large", ErrInvalidNotification)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := v.check(r.Context(), signature, body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNotification, err)
+	}
+	return body, nil
+}
+
+// check verifies the signature over body and records its ID
+func (v *NotificationVerifier) check(ctx context.Context, signature string, body []byte) error {
+	// Agents sign with their private key; a shared-secret algorithm would let anyone holding
+	// the published keys forge notifications
+	token, err := jose.Verify(ctx, signature, v.keys, []string{jose.RS256, jose.ES256})
+	if err != nil {
+		return err
+	}
+	var claims notificationClaims
+	if err := token.Claims(&claims); err != nil {
+		return err
+	}
+	now := v.now()
+	if err := claims.Validate(jose.Expectations{Audience: v.audience, Now: now, Leeway: notificationLeeway}); err != nil {
+		return err
+	}
+
+	issued := time.Unix(claims.IssuedAt, 0)
+	if claims.IssuedAt == 0 || now.Sub(issued) > v.maxAge+notificationLeeway || issued.Sub(now) > notificationLeeway {
+		return errors.New("notification is not fresh")
+	}
+	digest := sha256.Sum256(body)
+	if !strings.EqualFold(claims.BodySHA256, hex.EncodeToString(digest[:])) {
+		return errors.New("body does not match the signature")
+	}
+	if claims.ID == "" {
+		return errors.New("notification has no jti")
+	}
+	return v.remember(claims.ID, issued, now)
+}
+
+// remember records a notification ID, refusing one already seen. IDs are forgotten once their
+// notifications are too old to be accepted anyway.
+func (v *NotificationVerifier) remember(id string, issued, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for seenID, seenIssued := range v.seen {
+		if now.Sub(seenIssued) > v.maxAge+notificationLeeway {
+			delete(v.seen, seenID)
+		}
+	}
+	if _, replayed := v.seen[id]; replayed {
+		return errors.New("notification was already received")
+	}
+	v.seen[id] = issued
+	return nil
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"strings"
+
+	"a2a/models"
+)
+
+// Event is one update received on a task's event stream. Exactly one of Status and Artifact is set.
+type Event struct {
+	// ID is the SSE event ID, which can be passed to Client.Resubscribe to resume after it
+	ID       string
+	Status   *models.TaskStatusUpdateEvent
+	Artifact *models.TaskArtifactUpdateEvent
+}
+
+// Final reports whether this is the last event of the run
+func (e *Event) Final() bool {
+	switch {
+	case e.Status != nil:
+		return e.Status.Final != nil && *e.Status.Final
+	case e.Artifact != nil:
+		return e.Artifact.Final != nil && *e.Artifact.Final
+	default:
+		return false
+	}
+}
+
+// Stream reads the Server-Sent Events of a message/stream or tasks/resubscribe call
+type Stream struct {
+	body        io.ReadCloser
+	reader      *bufio.Reader
+	lastEventID string
+}
+
+func newStream(body io.ReadCloser) *Stream {
+	return &Stream{body: body, reader: bufio.NewReader(body)}
+}
+
+// Recv returns the next event. An error event sent by the agent is returned as an *RPCError,
+// after which the stream still delivers the run's final status. Recv returns io.EOF once the
+// agent ends the stream.
+func (s *Stream) Recv() (*Event, error) {
+	for {
+		id, data, err := s.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if id != "" {
+			s.lastEventID = id
+		}
+		if data == "" {
+			continue
+		}
+
+		var frame struct {
+			Result json.RawMessage `json:"result"`
+			Error  *RPCError       `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &frame); err != nil {
+			return nil, fmt.Errorf("decode stream event: %w", err)
+		}
+		if frame.Error != nil {
+			return nil, frame.Error
+		}
+		return decodeEvent(id, frame.Result)
+	}
+}
+
+// Events iterates over the stream until it ends. Error events sent by the agent are yielded as
+// *RPCError values and iteration continues; any other error is yielded once and ends it.
+func (s *Stream) Events() iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		for {
+			event, err := s.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			var rpcErr *RPCError
+			if !yield(event, err) || (err != nil && !errors.As(err, &rpcErr)) {
+				return
+			}
+		}
+	}
+}
+
+// LastEventID returns the ID of the last event received, for resuming with Client.Resubscribe
+func (s *Stream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Close releases the connection. The agent keeps working on the task.
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
+
+// readFrame reads lines up to the blank line that ends an SSE event and returns the event's
+// ID and data. Comment lines, such as keepalives, and other fields are skipped.
+func (s *Stream) readFrame() (string, string, error) {
+	var id string
+	var data []string
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			// An event cut off by the end of the stream is incomplete and is dropped
+			return "", "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if id != "" || len(data) > 0 {
+				return id, strings.Join(data, "\n"), nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			id = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+// decodeEvent turns the result of a stream event into a typed Event
+func decodeEvent(id string, result json.RawMessage) (*Event, error) {
+	var probe struct {
+		Artifact json.RawMessage `json:"artifact"`
+	}
+	if err := json.Unmarshal(result, &probe); err != nil {
+		return nil, fmt.Errorf("decode stream event: %w", err)
+	}
+
+	event := &Event{ID: id}
+	if len(probe.Artifact) > 0 {
+		event.Artifact = &models.TaskArtifactUpdateEvent{}
+		if err := json.Unmarshal(result, event.Artifact); err != nil {
+			return nil, fmt.Errorf("decode artifact event: %w", err)
+		}
+		return event, nil
+	}
+	event.Status = &models.TaskStatusUpdateEvent{}
+	if err := json.Unmarshal(result, event.Status); err != nil {
+		return nil, fmt.Errorf("decode status event: %w", err)
+	}
+	return event, nil
+}
+
+// isEventStream reports whether a Content-Type header denotes Server-Sent Events
+func isEventStream(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/event-stream"
+}
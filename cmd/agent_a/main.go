@@ -1,21 +1,34 @@
 package main
 
 import (
-	"a2a/models"
-	"bufio"
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"os"
 	"strings"
 	"time"
+
+	"a2a/client"
+	"a2a/models"
 )
 
+// 各 Agent 的基底網址；實際端點取自其發布的 Agent Card
+const (
+	financeBaseURL    = "http://localhost:8080/agent/finance"
+	complianceBaseURL = "http://localhost:8080/agent/compliance"
+)
+
+// taskID 每次執行都不同；已完成的任務不能再接收訊息
+var taskID = fmt.Sprintf("travel-task-%d", time.Now().Unix())
+
 func main() {
 	fmt.Println("🏢 [公司差旅展示] Agent A (助理) 正在啟動...")
 	time.Sleep(1 * time.Second)
 
+	ctx := context.Background()
+	financeEndpoint, finance := connect(ctx, financeBaseURL, client.WithBearerToken(financeToken()))
+	complianceEndpoint, compliance := connect(ctx, complianceBaseURL, client.WithBearerToken(requiredEnv("A2A_COMPLIANCE_TOKEN")))
+
 	// Step 1: 與 Agent B (財務) 互動
 	fmt.Println("\n=== Step 1: 與 Agent B (財務) 協調行程 ===")
 	rounds := []string{
@@ -27,146 +40,169 @@ func main() {
 
 	for i, cmd := range rounds {
 		fmt.Printf("\n--- 第 %d 回合 ---\n", i+1)
-		sendA2AMessage("http://localhost:8080/agent/finance", cmd)
+		sendA2AMessage(ctx, finance, financeEndpoint, cmd)
 		time.Sleep(1 * time.Second)
 	}
 
+	// 回顧目前為止與 Agent B 的對話，作為報帳摘要的依據
+	fmt.Println("\n--- 對話紀錄 ---")
+	printConversation(ctx, finance)
+
 	// Step 2: 取得 Agent B 的最終報告 (SSE)
 	fmt.Printf("\n--- 第 5 回合 (SSE 串流展示) ---\n")
 	fmt.Println("PA: 請產出最終行程表與報帳單。")
-	finalReport := streamA2AMessage("http://localhost:8080/agent/finance", "產出最終行程表與報帳單。")
-	
+	finalReport := streamA2AMessage(ctx, finance, "產出最終行程表與報帳單。")
+
 	// Step 3: 送交 Agent C (稽核) 審核
 	fmt.Println("\n=== Step 2: 送交 Agent C (稽核) 審核 ===")
 	time.Sleep(1 * time.Second)
-	
+
 	fmt.Printf("PA 發送報告給稽核: %s\n", finalReport)
-	
+
 	// 這裡我們直接把 Agent B 的輸出丟給 Agent C
 	// 在實際應用中，可能需要稍微整理格式，但 Agent C 的邏輯是 regex 金額，所以沒問題
-	sendA2AMessage("http://localhost:8080/agent/compliance", "請審核以下報表: " + finalReport)
+	sendA2AMessage(ctx, compliance, complianceEndpoint, "請審核以下報表: "+finalReport)
 }
 
-func sendA2AMessage(endpoint, text string) {
-	fmt.Printf("PA -> %s: %s\n", endpoint, text)
+// connect 先取得並驗證 Agent Card，再依卡片上的端點建立 client。
+// 卡片宣告需要驗證時才附上 credentials，避免把憑證送給不需要的 Agent。
+func connect(ctx context.Context, baseURL string, credentials ...client.Option) (string, *client.Client) {
+	card, err := client.ResolveCard(ctx, baseURL)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("🔎 發現 Agent: %s v%s (%s)\n", card.Name, card.Version, card.URL)
 
-	reqID := fmt.Sprintf("req-%d", time.Now().Unix())
-	params := models.TaskSendParams{
-		ID: "travel-task-123",
-		Message: models.Message{
-			Role: "user",
-			Parts: []models.Part{
-				{Text: &text},
-			},
-		},
+	if card.Authentication == nil || len(card.Authentication.Schemes) == 0 {
+		return card.URL, client.NewClient(card.URL)
 	}
+	fmt.Printf("🔐 %s 需要驗證: %s\n", card.Name, strings.Join(card.Authentication.Schemes, ", "))
+	return card.URL, client.NewClient(card.URL, credentials...)
+}
 
-	rpcReq := models.JSONRPCRequest{
-		JSONRPCMessage: models.JSONRPCMessage{
-			JSONRPC: "2.0",
-			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: reqID},
-		},
-		Method: "message/send",
-		Params: params,
+// financeToken 取得呼叫 Agent B 用的 JWT (A2A_FINANCE_TOKEN)。
+// 伺服器未設定 A2A_FINANCE_JWKS 時，會在啟動記錄中印出本次可用的展示 token
+func financeToken() string {
+	return requiredEnv("A2A_FINANCE_TOKEN")
+}
+
+// requiredEnv 讀取必要的環境變數，未設定時結束程式
+func requiredEnv(name string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		fail(fmt.Errorf("%s 未設定，請使用伺服器啟動記錄中印出的值", name))
 	}
+	return value
+}
 
-	body, _ := json.Marshal(rpcReq)
-	resp, err := http.Post(endpoint, "application/json", bytes.NewBuffer(body))
-	if err != nil {
+// userMessage 建立只含一段文字的使用者訊息
+func userMessage(text string) models.Message {
+	return models.Message{
+		Role:  "user",
+		Parts: []models.Part{{Text: &text}},
+	}
+}
+
+// fail 印出錯誤後結束程式；Agent 回傳的錯誤會附上錯誤碼
+func fail(err error) {
+	var rpcErr *client.RPCError
+	if errors.As(err, &rpcErr) {
+		fmt.Printf("錯誤 (%d): %s\n", rpcErr.Code, rpcErr.Message)
+	} else {
 		fmt.Printf("錯誤: %v\n", err)
-		panic(err)
 	}
-	defer func() { _ = resp.Body.Close() }()
+	os.Exit(1)
+}
+
+func sendA2AMessage(ctx context.Context, c *client.Client, endpoint, text string) {
+	fmt.Printf("PA -> %s: %s\n", endpoint, text)
+
+	task, err := c.SendMessage(ctx, models.TaskSendParams{
+		ID:      taskID,
+		Message: userMessage(text),
+	})
+	if err != nil {
+		fail(err)
+	}
+
+	// Agent 的回覆放在 status.message
+	fmt.Printf("RESPONSE: %s\n", messageText(task.Status.Message))
+}
 
-	var rpcResp models.JSONRPCResponse
-	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
-		fmt.Printf("Decode error: %v\n", err)
+// printConversation 透過 tasks/get 取回目前為止的完整對話 (使用者與 Agent 雙方)
+func printConversation(ctx context.Context, c *client.Client) {
+	task, err := c.GetTask(ctx, models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: taskID},
+	})
+	if err != nil {
+		fmt.Printf("無法取得對話紀錄: %v\n", err)
 		return
 	}
 
-	// 從 Metadata 中抓取我們剛才塞的回應
-	if res, ok := rpcResp.Result.(map[string]interface{}); ok {
-		if meta, ok := res["metadata"].(map[string]interface{}); ok {
-			fmt.Printf("RESPONSE: %v\n", meta["reply"])
-		}
+	fmt.Printf("目前對話共 %d 則訊息:\n", len(task.History))
+	for _, msg := range task.History {
+		fmt.Printf("  [%s] %s\n", msg.Role, messageText(&msg))
 	}
-}
 
-// 修改後的回傳值：返回最終累積的字串，供下一步驟使用
-func streamA2AMessage(endpoint, text string) string {
-	reqID := "req-stream-999"
-	params := models.TaskSendParams{
-		ID: "travel-task-123",
-		Message: models.Message{
-			Role: "user",
-			Parts: []models.Part{
-				{Text: &text},
-			},
-		},
+	// 狀態變化時間軸，供稽核追蹤每個階段的時間點
+	fmt.Println("任務狀態時間軸:")
+	for _, status := range task.StatusHistory {
+		fmt.Printf("  %s %s\n", status.Timestamp, status.State)
 	}
+}
 
-	rpcReq := models.JSONRPCRequest{
-		JSONRPCMessage: models.JSONRPCMessage{
-			JSONRPC: "2.0",
-			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: reqID},
-		},
-		Method: "message/stream",
-		Params: params,
+// messageText 串接訊息中所有文字片段
+func messageText(msg *models.Message) string {
+	if msg == nil {
+		return ""
 	}
+	var sb strings.Builder
+	for _, part := range msg.Parts {
+		if part.Text != nil {
+			sb.WriteString(*part.Text)
+		}
+	}
+	return sb.String()
+}
 
-	body, _ := json.Marshal(rpcReq)
-	resp, err := http.Post(endpoint, "application/json", bytes.NewBuffer(body))
+// 修改後的回傳值：返回最終累積的字串，供下一步驟使用
+func streamA2AMessage(ctx context.Context, c *client.Client, text string) string {
+	stream, err := c.StreamMessage(ctx, models.TaskSendParams{
+		ID:      taskID,
+		Message: userMessage(text),
+	})
 	if err != nil {
-		fmt.Printf("錯誤: %v\n", err)
-		panic(err)
+		fail(err)
 	}
-	defer func() { _ = resp.Body.Close() }()
+	defer func() { _ = stream.Close() }()
 
 	fmt.Println(">>> 正在接收即時進度更新 (SSE)...")
-	
+
 	fullText := ""
-	
-	reader := bufio.NewReader(resp.Body)
-	for {
-		line, err := reader.ReadString('\n')
+	for event, err := range stream.Events() {
+		var rpcErr *client.RPCError
+		if errors.As(err, &rpcErr) {
+			fmt.Printf("\n❌ 串流錯誤 (%d): %s\n", rpcErr.Code, rpcErr.Message)
+			continue
+		}
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return ""
+			fail(err)
 		}
-		
-		if strings.TrimSpace(line) == "" {
-			continue
+
+		// 處理 1: 文字碎片
+		if event.Artifact != nil {
+			txt := messageText(&models.Message{Parts: event.Artifact.Artifact.Parts})
+			fmt.Print(txt)
+			fullText += txt
 		}
 
-		var streamResp models.SendTaskStreamingResponse
-		if err := json.Unmarshal([]byte(line), &streamResp); err == nil {
-			if update, ok := streamResp.Result.(map[string]interface{}); ok {
-				// 處理 1: 狀態更新
-				if _, ok := update["status"].(map[string]interface{}); ok {
-					if update["final"] != true {
-						_ = 0
-					}
-				}
-				
-				// 處理 2: 文字碎片
-				if artifact, ok := update["artifact"].(map[string]interface{}); ok {
-					if parts, ok := artifact["parts"].([]interface{}); ok && len(parts) > 0 {
-						if part, ok := parts[0].(map[string]interface{}); ok {
-							if txt, ok := part["text"].(string); ok {
-								fmt.Print(txt)
-								fullText += txt
-							}
-						}
-					}
-				}
-
-				if update["final"] == true {
-					fmt.Println("\n\n✅ 任務完整結束！")
-					break
-				}
+		// 處理 2: 最終狀態，Agent 的完整回覆放在 status.message
+		if event.Final() {
+			if event.Status != nil && event.Status.Status.Message != nil {
+				fullText = messageText(event.Status.Status.Message)
 			}
+			fmt.Println("\n\n✅ 任務完整結束！")
+			break
 		}
 	}
 	return fullText
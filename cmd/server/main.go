@@ -1,28 +1,96 @@
 package main
 
 import (
-	"a2a/internal/agents"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"a2a/internal/agents"
+	"a2a/server"
 )
 
+// agentFactories maps the agent names a config file can use to their implementations
+var agentFactories = map[string]func(...server.Option) *server.A2AServer{
+	"finance":    agents.NewFinanceAgent,
+	"compliance": agents.NewComplianceAgent,
+}
+
+// defaultConfig serves Agent B and Agent C on :8080 with durable task storage,
+// which is what runs when no config file is given
+func defaultConfig() server.Config {
+	return server.Config{
+		Listen:  ":8080",
+		DataDir: "data",
+		Agents: []server.AgentConfig{
+			{Name: "finance", Path: "/agent/finance"},
+			{Name: "compliance", Path: "/agent/compliance"},
+		},
+	}
+}
+
 func main() {
-	// 1. Initialize Agents
-	financeAgent := agents.NewFinanceAgent()
-	complianceAgent := agents.NewComplianceAgent()
-
-	// 2. Register Routes (Single Port, Multiple Paths)
-	http.Handle("/agent/finance", financeAgent)
-	http.Handle("/agent/compliance", complianceAgent)
-
-	// 3. Start Server
-	port := ":8080"
-	fmt.Printf("🚀 A2A Server Cluster Started on %s\n", port)
-	fmt.Println("   - Agent B (Finance):    http://localhost:8080/agent/finance")
-	fmt.Println("   - Agent C (Compliance): http://localhost:8080/agent/compliance")
-	
-	if err := http.ListenAndServe(port, nil); err != nil {
+	configPath := flag.String("config", os.Getenv("A2A_CONFIG"), "JSON config file (default: built-in finance and compliance agents)")
+	flag.Parse()
+
+	// 1. Load settings: built-in defaults, then the config file, then A2A_* environment variables
+	cfg := defaultConfig()
+	if *configPath != "" {
+		if err := cfg.LoadFile(*configPath); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+	if err := cfg.LoadEnv(os.LookupEnv); err != nil {
+		log.Fatalf("Invalid environment: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	// 2. Initialize Agents, each with its own task store and log prefix
+	host := server.NewHost(cfg.Listen, append(cfg.HostOptions(), server.WithMiddleware(logRequests))...)
+	for _, agentCfg := range cfg.Agents {
+		newAgent, ok := agentFactories[agentCfg.Name]
+		if !ok {
+			log.Fatalf("Unknown agent %q in config", agentCfg.Name)
+		}
+		opts, err := cfg.AgentOptions(agentCfg)
+		if err != nil {
+			log.Fatalf("Failed to configure %s: %v", agentCfg.Name, err)
+		}
+		logger := log.New(os.Stderr, "["+agentCfg.Name+"] ", log.LstdFlags)
+		host.Handle(agentCfg.Path, newAgent(append(opts, server.WithLogger(logger))...))
+	}
+
+	// 3. Start Server; SIGINT or SIGTERM drains in-flight streams and queued messages first
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("🚀 A2A Server Cluster Started on %s\n", cfg.Listen)
+	for _, agentCfg := range cfg.Agents {
+		fmt.Printf("   - %-21s %s\n", agentCfg.Name+":", agentCfg.Path)
+	}
+	fmt.Println("   - Agent registry:       /agents")
+	fmt.Println("   - Health checks:        /healthz, /readyz")
+	if cfg.AdminToken != "" {
+		fmt.Printf("   - Push deliveries:      %s\n", server.DeliveryAdminPath)
+	}
+	fmt.Printf("   - Task data:            %s\n", cfg.DataDir)
+
+	if err := host.ListenAndServe(ctx); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
+	fmt.Println("👋 A2A Server Cluster stopped")
+}
+
+// logRequests logs each request as it arrives
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
 }
@@ -1,15 +1,33 @@
 package agents
 
 import (
-	"a2a/models"
-	"a2a/server"
+	"context"
+	"crypto/rand"
 	"fmt"
+	"log"
+	"os"
 	"strings"
+	"sync"
 	"time"
+
+	"a2a/models"
+	"a2a/server"
 )
 
+// complianceToken returns the bearer token callers of Agent C must present: A2A_COMPLIANCE_TOKEN,
+// or else a random token generated once per process and logged so a local demo can hand it to
+// Agent A
+var complianceToken = sync.OnceValue(func() string {
+	if token := os.Getenv("A2A_COMPLIANCE_TOKEN"); token != "" {
+		return token
+	}
+	token := rand.Text()
+	log.Printf("A2A_COMPLIANCE_TOKEN is not set; Agent C accepts a token generated for this run: A2A_COMPLIANCE_TOKEN=%s", token)
+	return token
+})
+
 // ComplianceAgent (Agent C)
-func NewComplianceAgent() *server.A2AServer {
+func NewComplianceAgent(opts ...server.Option) *server.A2AServer {
 	card := models.AgentCard{
 		Name:        "ComplianceOfficer",
 		Description: models.StringPtr("稽核專員，負責審查最終報表是否合規"),
@@ -18,22 +36,33 @@ func NewComplianceAgent() *server.A2AServer {
 		Capabilities: models.AgentCapabilities{
 			Streaming: models.BoolPtr(false),
 		},
+		// 稽核結果具有效力，只接受持有 token 的呼叫者
+		Authentication: &models.AgentAuthentication{Schemes: []string{server.SchemeBearer}},
 		Skills: []models.AgentSkill{
 			{ID: "audit-report", Name: "報表稽核", Description: models.StringPtr("審查報支金額")},
 		},
 	}
 
-	handler := func(task *models.Task, msg *models.Message, update func(any)) (*models.Task, error) {
+	handler := func(ctx context.Context, task *models.Task, msg *models.Message, update func(any)) (*models.Task, error) {
 		text := ""
 		if len(msg.Parts) > 0 && msg.Parts[0].Text != nil {
 			text = *msg.Parts[0].Text
 		}
 
-		fmt.Printf("[Agent C (Compliance)] 收到指令: %s\n", text)
-		
+		caller := "unknown"
+		if principal, ok := server.PrincipalFromContext(ctx); ok {
+			caller = principal.Subject
+		}
+		fmt.Printf("[Agent C (Compliance)] 收到 %s 的指令: %s\n", caller, text)
+
 		// 模擬稽核邏輯
-		time.Sleep(1 * time.Second) // 模擬審查時間
-		
+		// 模擬審查時間，任務取消時立即停止
+		select {
+		case <-time.After(1 * time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
 		var responseText string
 		if strings.Contains(text, "$15,500") || strings.Contains(text, "15,500") {
 			responseText = "✅ [核准] 總金額 $15,500 符合部門預算 ($20,000)。核准代碼: COMP-2026-OK"
@@ -44,13 +73,17 @@ func NewComplianceAgent() *server.A2AServer {
 		}
 
 		task.Status.State = models.TaskStateCompleted
-		if task.Metadata == nil {
-			task.Metadata = make(map[string]interface{})
+		task.Status.Message = &models.Message{
+			Role:  "agent",
+			Parts: []models.Part{{Text: &responseText}},
 		}
-		task.Metadata["reply"] = responseText
 
 		return task, nil
 	}
 
-	return server.NewA2AServer(card, handler)
+	// 驗證放在最前面，呼叫端仍可用自己的 WithCardAuth 覆蓋
+	auth := server.WithCardAuth(server.AuthValidators{
+		Bearer: server.StaticTokens(map[string]string{complianceToken(): "agent-a"}),
+	})
+	return server.NewA2AServer(card, handler, append([]server.Option{auth}, opts...)...)
 }
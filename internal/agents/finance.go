@@ -1,30 +1,132 @@
 package agents
 
 import (
-	"a2a/models"
-	"a2a/server"
+	"context"
+	"crypto/rand"
 	"fmt"
+	"log"
+	"os"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+
+	"a2a/internal/jose"
+	"a2a/models"
+	"a2a/server"
+)
+
+const (
+	// financeAudience is the aud claim tokens for Agent B must carry
+	financeAudience = "finance-agent"
+	// devJWTIssuer signs the demo tokens when A2A_JWT_ISSUER is not set
+	devJWTIssuer = "a2a-demo"
+	// devTokenLifetime is how long the demo token logged at startup stays valid
+	devTokenLifetime = 24 * time.Hour
+	// skillBudgetCheck may only be used by callers whose scope claim lists it
+	skillBudgetCheck = "budget-check"
 )
 
+// jwtIssuer returns the issuer Agent B trusts
+func jwtIssuer() string {
+	if issuer := os.Getenv("A2A_JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return devJWTIssuer
+}
+
+// financeKeys returns the keys Agent B verifies tokens with: the JWKS file or endpoint named by
+// A2A_FINANCE_JWKS, or else a random HS256 key that only lives as long as the process. A JWKS
+// that cannot be loaded refuses every token.
+func financeKeys() server.KeySet {
+	source := os.Getenv("A2A_FINANCE_JWKS")
+	if source == "" {
+		return jose.StaticKeySet{Set: &jose.JWKS{Keys: []jose.JWK{jose.SecretJWK(devFinanceSecret(), "dev")}}}
+	}
+	keys, err := server.LoadJWKS(source)
+	if err != nil {
+		log.Printf("Failed to load JWKS for Agent B, refusing all tokens: %v", err)
+		return jose.StaticKeySet{Set: &jose.JWKS{}}
+	}
+	return keys
+}
+
+// devFinanceSecret generates the demo HS256 key once per process. No key is ever shipped with
+// the code; instead a token signed with it is logged, so a local demo can hand it to Agent A
+// through A2A_FINANCE_TOKEN.
+var devFinanceSecret = sync.OnceValue(func() []byte {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+
+	token, err := signDevFinanceToken(secret, "agent-a", "travel-booking budget-check")
+	if err != nil {
+		log.Printf("A2A_FINANCE_JWKS is not set and no demo token could be signed: %v", err)
+		return secret
+	}
+	log.Printf("A2A_FINANCE_JWKS is not set; Agent B accepts tokens signed with a key generated for this run. "+
+		"Demo token (valid %v): A2A_FINANCE_TOKEN=%s", devTokenLifetime, token)
+	return secret
+})
+
+// signDevFinanceToken signs a token for Agent B with the demo key, granting scope (space
+// separated skill IDs)
+func signDevFinanceToken(secret []byte, subject, scope string) (string, error) {
+	now := time.Now()
+	claims := struct {
+		jose.Claims
+		Scope string `json:"scope"`
+	}{
+		Claims: jose.Claims{
+			Issuer:    jwtIssuer(),
+			Subject:   subject,
+			Audience:  jose.Audience{financeAudience},
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(devTokenLifetime).Unix(),
+		},
+		Scope: scope,
+	}
+	return jose.Sign(jose.Header{Alg: jose.HS256, Kid: "dev"}, claims, secret)
+}
+
+// requestedSkill picks the skill a message asks for: questions about totals and expense
+// reports need budget-check, everything else is travel booking
+func requestedSkill(text string) string {
+	if strings.Contains(text, "總費用") || strings.Contains(text, "報帳") {
+		return skillBudgetCheck
+	}
+	return "travel-booking"
+}
+
+// skillAllowed reports whether the caller may use skill; budget-check needs the scope claim to grant it
+func skillAllowed(ctx context.Context, skill string) bool {
+	if skill != skillBudgetCheck {
+		return true
+	}
+	principal, ok := server.PrincipalFromContext(ctx)
+	return ok && slices.Contains(principal.ClaimValues("scope"), skill)
+}
+
 // FinanceAgent (Agent B)
-func NewFinanceAgent() *server.A2AServer {
+func NewFinanceAgent(opts ...server.Option) *server.A2AServer {
 	card := models.AgentCard{
 		Name:        "FinanceTravelExpert",
 		Description: models.StringPtr("專門處理公司差旅預算與訂票的財務助理"),
 		Version:     "1.0.0",
 		URL:         "http://localhost:8080/agent/finance",
 		Capabilities: models.AgentCapabilities{
-			Streaming: models.BoolPtr(true),
+			Streaming:              models.BoolPtr(true),
+			PushNotifications:      models.BoolPtr(true),
+			StateTransitionHistory: models.BoolPtr(true),
 		},
+		// 呼叫者需出示 JWT；budget-check 另需 scope 授權
+		Authentication: &models.AgentAuthentication{Schemes: []string{server.SchemeBearer}},
 		Skills: []models.AgentSkill{
 			{ID: "travel-booking", Name: "差旅訂票", Description: models.StringPtr("處理飯店與高鐵訂位")},
 			{ID: "budget-check", Name: "預算審核", Description: models.StringPtr("確保開支符合公司政策")},
 		},
 	}
 
-	handler := func(task *models.Task, msg *models.Message, update func(any)) (*models.Task, error) {
+	handler := func(ctx context.Context, task *models.Task, msg *models.Message, update func(any)) (*models.Task, error) {
 		text := ""
 		if len(msg.Parts) > 0 && msg.Parts[0].Text != nil {
 			text = *msg.Parts[0].Text
@@ -32,10 +134,14 @@ func NewFinanceAgent() *server.A2AServer {
 
 		fmt.Printf("[Agent B (Finance)] 收到指令: %s\n", text)
 
-		responseState := models.TaskStateWorking
+		// 對話進行中，等待使用者下一步指示
+		responseState := models.TaskStateInputRequired
 		var responseText string
 
 		switch {
+		case !skillAllowed(ctx, requestedSkill(text)):
+			// 未授權的技能不讓任務失敗，保留對話讓呼叫者改提其他需求
+			responseText = "您的 token 未授權使用「預算審核」(budget-check)，無法查詢總費用或報帳。請改用具備此權限的 token，或提出其他需求。"
 		case strings.Contains(text, "下週一"):
 			responseText = "【第一回合】已為您找到兩間符合政策的飯店：1. 君悅 ($4,800) 2. 寒舍艾美 ($5,000)。請問要訂哪一間？"
 		case strings.Contains(text, "君悅"):
@@ -44,12 +150,11 @@ func NewFinanceAgent() *server.A2AServer {
 			responseText = "【第三回合】機票與飯店已確認，總計 $15,500。請問此行出差事由為何？財務部報支需要。"
 		case strings.Contains(text, "研討會"):
 			responseText = "【第四回合】收到。我現在開始為您準備完整的行程摘要與報帳草案，請稍候..."
-			responseState = models.TaskStateCompleted
 		case strings.Contains(text, "產出"):
 			// 模擬打字機效果的串流輸出
 			report := "【最終行程報告】\n- 飯店：君悅飯店 (3晚)\n- 交通：高鐵台中-台北來回\n- 事由：A2A技術研討會\n- 總預算：$15,500\n✅ 報帳單已產出並歸檔。"
-			
-			for _, charRune := range report {
+
+			for i, charRune := range []rune(report) {
 				char := string(charRune)
 				update(models.TaskArtifactUpdateEvent{
 					ID: task.ID,
@@ -57,12 +162,19 @@ func NewFinanceAgent() *server.A2AServer {
 						Parts: []models.Part{
 							{Text: &char},
 						},
+						Index:  models.IntPtr(0),
+						Append: models.BoolPtr(i > 0),
 					},
 					Final: models.BoolPtr(false),
 				})
-				time.Sleep(20 * time.Millisecond) // Slightly faster for demo
+				// Slightly faster for demo; stop typing as soon as the task is canceled
+				select {
+				case <-time.After(20 * time.Millisecond):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
 			}
-			
+
 			responseText = report // Return full report as final result
 			responseState = models.TaskStateCompleted
 		default:
@@ -70,13 +182,21 @@ func NewFinanceAgent() *server.A2AServer {
 		}
 
 		task.Status.State = responseState
-		if task.Metadata == nil {
-			task.Metadata = make(map[string]interface{})
+		task.Status.Message = &models.Message{
+			Role:  "agent",
+			Parts: []models.Part{{Text: &responseText}},
 		}
-		task.Metadata["reply"] = responseText
 
 		return task, nil
 	}
 
-	return server.NewA2AServer(card, handler)
+	auth := server.WithCardAuth(server.AuthValidators{
+		Bearer: server.JWTValidator(server.JWTConfig{
+			Keys:     financeKeys(),
+			Issuer:   jwtIssuer(),
+			Audience: financeAudience,
+			Claims:   map[string]string{"scope": "scope"},
+		}),
+	})
+	return server.NewA2AServer(card, handler, append([]server.Option{auth}, opts...)...)
 }
@@ -0,0 +1,84 @@
+package jose
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Claims are the registered JWT claims (RFC 7519). Times are seconds since the epoch; zero
+// means the claim is absent.
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+}
+
+// Audience is the aud claim, which may be a single string or a list in JSON
+type Audience []string
+
+// MarshalJSON writes a single audience as a plain string
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON accepts a string or a list of strings
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("aud must be a string or a list of strings")
+	}
+	*a = list
+	return nil
+}
+
+// Expectations are what Validate checks claims against
+type Expectations struct {
+	// Issuer, when set, must equal iss
+	Issuer string
+	// Audience, when set, must be one of aud
+	Audience string
+	// Now is the time to check against; zero means time.Now
+	Now time.Time
+	// Leeway tolerates clock skew between the issuer and this process
+	Leeway time.Duration
+}
+
+// Validate checks the issuer, audience, expiry and not-before time. Tokens without an expiry
+// are refused, since a stolen one would be valid forever.
+func (c *Claims) Validate(expect Expectations) error {
+	now := expect.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if expect.Issuer != "" && c.Issuer != expect.Issuer {
+		return fmt.Errorf("unexpected issuer %q", c.Issuer)
+	}
+	if expect.Audience != "" && !slices.Contains(c.Audience, expect.Audience) {
+		return fmt.Errorf("token is not meant for audience %q", expect.Audience)
+	}
+	if c.ExpiresAt == 0 {
+		return errors.New("token has no expiry")
+	}
+	if now.Add(-expect.Leeway).After(time.Unix(c.ExpiresAt, 0)) {
+		return errors.New("token has expired")
+	}
+	if c.NotBefore != 0 && now.Add(expect.Leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return errors.New("token is not valid yet")
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+package jose
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testKeys holds a freshly generated key of every supported type
+type testKeys struct {
+	rsa    *rsa.PrivateKey
+	ec     *ecdsa.PrivateKey
+	secret []byte
+	set    *JWKS
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	rsaJWK, err := PublicJWK(&rsaKey.PublicKey, "rsa-1")
+	if err != nil {
+		t.Fatalf("PublicJWK(RSA) failed: %v", err)
+	}
+	ecJWK, err := PublicJWK(&ecKey.PublicKey, "ec-1")
+	if err != nil {
+		t.Fatalf("PublicJWK(EC) failed: %v", err)
+	}
+	secret := []byte("test-secret-of-reasonable-length")
+	return testKeys{
+		rsa:    rsaKey,
+		ec:     ecKey,
+		secret: secret,
+		set:    &JWKS{Keys: []JWK{rsaJWK, ecJWK, SecretJWK(secret, "hs-1")}},
+	}
+}
+
+func testClaims() Claims {
+	now := time.Now()
+	return Claims{Issuer: "issuer", Subject: "agent-a", Audience: Audience{"finance"}, IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	tests := []struct {
+		alg string
+		kid string
+		key any
+	}{
+		{RS256, "rsa-1", keys.rsa},
+		{ES256, "ec-1", keys.ec},
+		{HS256, "hs-1", keys.secret},
+		{RS256, "", keys.rsa},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alg+"/"+tt.kid, func(t *testing.T) {
+			token, err := Sign(Header{Alg: tt.alg, Kid: tt.kid}, testClaims(), tt.key)
+			if err != nil {
+				t.Fatalf("Sign failed: %v", err)
+			}
+			parsed, err := Verify(context.Background(), token, StaticKeySet{Set: keys.set}, Algorithms)
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+			var claims Claims
+			if err := parsed.Claims(&claims); err != nil || claims.Subject != "agent-a" || claims.Audience[0] != "finance" {
+				t.Errorf("Expected the signed claims back, got %+v (%v)", claims, err)
+			}
+
+			// Any change to the payload breaks the signature
+			parts := strings.Split(token, ".")
+			forged, _ := json.Marshal(Claims{Subject: "mallory", ExpiresAt: claims.ExpiresAt})
+			parts[1] = encodeSegment(forged)
+			if _, err := Verify(context.Background(), strings.Join(parts, "."), StaticKeySet{Set: keys.set}, Algorithms); !errors.Is(err, ErrSignature) {
+				t.Errorf("Expected a signature error for a forged payload, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerify_Rejections(t *testing.T) {
+	keys := newTestKeys(t)
+	set := StaticKeySet{Set: keys.set}
+	ctx := context.Background()
+
+	// An RSA public key must never serve as an HMAC secret
+	rsaJWK := keys.set.Keys[0]
+	token, err := Sign(Header{Alg: HS256, Kid: "rsa-1"}, testClaims(), []byte(rsaJWK.N))
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if _, err := Verify(ctx, token, set, Algorithms); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected algorithm confusion to find no key, got %v", err)
+	}
+
+	// alg "none" and algorithms outside the accepted list are refused
+	unsigned := encodeSegment([]byte(`{"alg":"none"}`)) + "." + encodeSegment([]byte(`{"sub":"x"}`)) + "."
+	if _, err := Verify(ctx, unsigned, set, Algorithms); err == nil {
+		t.Error("Expected an unsigned token to be refused")
+	}
+	token, _ = Sign(Header{Alg: HS256, Kid: "hs-1"}, testClaims(), keys.secret)
+	if _, err := Verify(ctx, token, set, []string{RS256}); err == nil {
+		t.Error("Expected an algorithm outside the accepted list to be refused")
+	}
+
+	if _, err := Verify(ctx, "not-a-token", set, Algorithms); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected a malformed token error, got %v", err)
+	}
+
+	// A key from another set does not verify
+	other := newTestKeys(t)
+	token, _ = Sign(Header{Alg: ES256, Kid: "ec-1"}, testClaims(), other.ec)
+	if _, err := Verify(ctx, token, set, Algorithms); !errors.Is(err, ErrSignature) {
+		t.Errorf("Expected a signature error, got %v", err)
+	}
+}
+
+func TestClaims_Validate(t *testing.T) {
+	now := time.Now()
+	expect := Expectations{Issuer: "issuer", Audience: "finance", Now: now, Leeway: time.Minute}
+
+	tests := []struct {
+		name   string
+		modify func(c *Claims)
+		valid  bool
+	}{
+		{"valid", func(c *Claims) {}, true},
+		{"audience list", func(c *Claims) { c.Audience = Audience{"other", "finance"} }, true},
+		{"wrong issuer", func(c *Claims) { c.Issuer = "someone" }, false},
+		{"wrong audience", func(c *Claims) { c.Audience = Audience{"compliance"} }, false},
+		{"no expiry", func(c *Claims) { c.ExpiresAt = 0 }, false},
+		{"expired", func(c *Claims) { c.ExpiresAt = now.Add(-2 * time.Minute).Unix() }, false},
+		{"expired within leeway", func(c *Claims) { c.ExpiresAt = now.Add(-30 * time.Second).Unix() }, true},
+		{"not yet valid", func(c *Claims) { c.NotBefore = now.Add(2 * time.Minute).Unix() }, false},
+		{"not before within leeway", func(c *Claims) { c.NotBefore = now.Add(30 * time.Second).Unix() }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := testClaims()
+			tt.modify(&claims)
+			err := claims.Validate(expect)
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid claims, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected the claims to be refused")
+			}
+		})
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	keys := newTestKeys(t)
+	data, err := json.Marshal(keys.set.Public())
+	if err != nil {
+		t.Fatalf("Failed to encode JWKS: %v", err)
+	}
+	if strings.Contains(string(data), `"oct"`) {
+		t.Fatal("Expected the public set to leave out secrets")
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+
+	set, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	token, _ := Sign(Header{Alg: RS256, Kid: "rsa-1"}, testClaims(), keys.rsa)
+	if _, err := Verify(context.Background(), token, set, Algorithms); err != nil {
+		t.Errorf("Expected the file's keys to verify, got %v", err)
+	}
+
+	if _, err := LoadKeySet(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	if _, err := ParseJWKS([]byte(`{"keys":[{"kty":"EC","crv":"P-384","x":"AA","y":"AA"}]}`)); err == nil {
+		t.Error("Expected an error for an unsupported curve")
+	}
+}
+
+func TestParseJWKS_SkipsUnusableKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	mixed := struct {
+		Keys []any `json:"keys"`
+	}{Keys: []any{
+		map[string]string{"kty": "EC", "kid": "p384", "crv": "P-384", "x": "AA", "y": "AA"},
+		map[string]string{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": "AA"},
+		map[string]string{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": keys.set.Keys[0].N, "e": keys.set.Keys[0].E},
+		keys.set.Keys[0],
+	}}
+	data, _ := json.Marshal(mixed)
+
+	set, err := ParseJWKS(data)
+	if err != nil {
+		t.Fatalf("Expected the usable key to be kept, got %v", err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].Kid != "rsa-1" {
+		t.Fatalf("Expected only rsa-1, got %+v", set.Keys)
+	}
+	token, _ := Sign(Header{Alg: RS256, Kid: "rsa-1"}, testClaims(), keys.rsa)
+	if _, err := Verify(context.Background(), token, StaticKeySet{Set: set}, Algorithms); err != nil {
+		t.Errorf("Expected the kept key to verify, got %v", err)
+	}
+}
+
+func TestRemoteKeySet_RefreshesForUnknownKey(t *testing.T) {
+	keys := newTestKeys(t)
+	var current atomic.Pointer[JWKS]
+	current.Store(&JWKS{Keys: keys.set.Keys[:1]})
+	var fetches atomic.Int32
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(current.Load())
+	}))
+	defer endpoint.Close()
+
+	set, err := LoadKeySet(endpoint.URL)
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	ctx := context.Background()
+	token, _ := Sign(Header{Alg: RS256, Kid: "rsa-1"}, testClaims(), keys.rsa)
+	for range 2 {
+		if _, err := Verify(ctx, token, set, Algorithms); err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected the set to be cached, got %d fetches", fetches.Load())
+	}
+
+	// The issuer rotates in a new key; a token signed with it makes the set refresh, but
+	// only once per refresh interval
+	current.Store(keys.set)
+	remote := set.(*RemoteKeySet)
+	remote.mu.Lock()
+	remote.fetched = time.Now().Add(-remoteMinRefresh)
+	remote.mu.Unlock()
+
+	token, _ = Sign(Header{Alg: ES256, Kid: "ec-1"}, testClaims(), keys.ec)
+	if _, err := Verify(ctx, token, set, Algorithms); err != nil {
+		t.Fatalf("Expected the rotated key to verify after a refresh, got %v", err)
+	}
+	unknown, _ := Sign(Header{Alg: ES256, Kid: "ec-unknown"}, testClaims(), keys.ec)
+	if _, err := Verify(ctx, unknown, set, Algorithms); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected no key for an unknown kid, got %v", err)
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("Expected exactly one refresh, got %d fetches", fetches.Load())
+	}
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	keys := newTestKeys(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(keys.ec)
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	tests := []struct {
+		name  string
+		block *pem.Block
+		alg   string
+	}{
+		{"PKCS #1", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(keys.rsa)}, RS256},
+		{"PKCS #8", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}, ES256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePrivateKeyPEM(pem.EncodeToMemory(tt.block))
+			if err != nil {
+				t.Fatalf("ParsePrivateKeyPEM failed: %v", err)
+			}
+			if alg, _ := SigningAlgorithm(key); alg != tt.alg {
+				t.Errorf("Expected %s, got %s", tt.alg, alg)
+			}
+		})
+	}
+
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	der, _ := x509.MarshalECPrivateKey(p384)
+	if _, err := ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err == nil {
+		t.Error("Expected an error for an unsupported curve")
+	}
+	if _, err := ParsePrivateKeyPEM([]byte("not pem")); err == nil {
+		t.Error("Expected an error for data without a PEM block")
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	// The example key of RFC 7638, section 3.1
+	jwk := JWK{
+		Kty: KeyTypeRSA,
+		Kid: "2011-04-29",
+		Alg: RS256,
+		E:   "AQAB",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECP" +
+			"ebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368" +
+			"QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2" +
+			"NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+	thumbprint, err := Thumbprint(jwk)
+	if err != nil {
+		t.Fatalf("Thumbprint failed: %v", err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("Expected the RFC 7638 thumbprint, got %s", thumbprint)
+	}
+	if _, err := Thumbprint(SecretJWK([]byte("secret"), "")); err == nil {
+		t.Error("Expected an error for a secret key")
+	}
+}
+
+func TestRemoteKeySet_FetchesOutsideTheLock(t *testing.T) {
+	keys := newTestKeys(t)
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		_ = json.NewEncoder(w).Encode(keys.set.Public())
+	}))
+	defer endpoint.Close()
+
+	remote := NewRemoteKeySet(endpoint.URL, nil)
+	ctx := context.Background()
+	if _, err := remote.Keys(ctx, false); err != nil {
+		t.Fatalf("Keys failed: %v", err)
+	}
+	remote.mu.Lock()
+	remote.fetched = time.Now().Add(-remoteMinRefresh)
+	remote.mu.Unlock()
+
+	// Several tokens with a new kid arrive while the endpoint is slow; they share one fetch
+	refreshed := make(chan error, 5)
+	for range 5 {
+		go func() {
+			_, err := remote.Keys(ctx, true)
+			refreshed <- err
+		}()
+	}
+	waitFetches := time.Now().Add(2 * time.Second)
+	for fetches.Load() < 2 && time.Now().Before(waitFetches) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Meanwhile tokens with known keys are checked against the cache without waiting
+	done := make(chan error, 1)
+	go func() {
+		_, err := remote.Keys(ctx, false)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Keys failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected cached keys while a fetch is in flight")
+	}
+
+	close(release)
+	for range 5 {
+		if err := <-refreshed; err != nil {
+			t.Errorf("Refresh failed: %v", err)
+		}
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("Expected one shared refresh, got %d fetches", fetches.Load())
+	}
+}
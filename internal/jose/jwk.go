@@ -0,0 +1,195 @@
+// Package jose implements the parts of JSON Web Keys (RFC 7517) and compact JSON Web Signatures
+// (RFC 7515) the A2A server and client need: RS256, ES256 and HS256 tokens, verified against a
+// JWKS read from a file or an endpoint.
+package jose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Key types
+const (
+	KeyTypeRSA = "RSA"
+	KeyTypeEC  = "EC"
+	KeyTypeOct = "oct"
+)
+
+// JWK is a JSON Web Key. Only the public members are kept for RSA and EC keys; oct keys carry
+// their secret in K.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// N and E are the RSA modulus and exponent
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv, X and Y are the EC curve and point
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	// K is the secret of an oct key
+	K string `json:"k,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWK describes an RSA or P-256 public key for publishing in a JWKS
+func PublicJWK(key crypto.PublicKey, kid string) (JWK, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: KeyTypeRSA,
+			Kid: kid,
+			Use: "sig",
+			Alg: RS256,
+			N:   encodeSegment(key.N.Bytes()),
+			E:   encodeSegment(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return JWK{}, fmt.Errorf("unsupported curve %s", key.Curve.Params().Name)
+		}
+		point, err := key.Bytes()
+		if err != nil {
+			return JWK{}, err
+		}
+		// The uncompressed point is 0x04 || X || Y
+		return JWK{
+			Kty: KeyTypeEC,
+			Kid: kid,
+			Use: "sig",
+			Alg: ES256,
+			Crv: "P-256",
+			X:   encodeSegment(point[1:33]),
+			Y:   encodeSegment(point[33:]),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// SecretJWK describes an HS256 shared secret. Sets holding secrets must never be published.
+func SecretJWK(secret []byte, kid string) JWK {
+	return JWK{Kty: KeyTypeOct, Kid: kid, Use: "sig", Alg: HS256, K: encodeSegment(secret)}
+}
+
+// verificationKey decodes the key material: *rsa.PublicKey, *ecdsa.PublicKey or []byte
+func (k JWK) verificationKey() (any, error) {
+	switch k.Kty {
+	case KeyTypeRSA:
+		n, err := decodeSegment(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: modulus: %w", k.Kid, err)
+		}
+		e, err := decodeSegment(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: exponent: %w", k.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("jwk %s: invalid RSA key", k.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case KeyTypeEC:
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("jwk %s: unsupported curve %q", k.Kid, k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: x: %w", k.Kid, err)
+		}
+		y, err := decodeSegment(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: y: %w", k.Kid, err)
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("jwk %s: invalid P-256 point", k.Kid)
+		}
+		point := append(append([]byte{4}, x...), y...)
+		key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: %w", k.Kid, err)
+		}
+		return key, nil
+	case KeyTypeOct:
+		secret, err := decodeSegment(k.K)
+		if err != nil || len(secret) == 0 {
+			return nil, fmt.Errorf("jwk %s: invalid secret", k.Kid)
+		}
+		return secret, nil
+	default:
+		return nil, fmt.Errorf("jwk %s: unsupported key type %q", k.Kid, k.Kty)
+	}
+}
+
+// Public returns the set without its oct keys, which are secrets, so it can be published
+func (s *JWKS) Public() *JWKS {
+	public := &JWKS{Keys: []JWK{}}
+	for _, key := range s.Keys {
+		if key.Kty != KeyTypeOct {
+			public.Keys = append(public.Keys, key)
+		}
+	}
+	return public
+}
+
+// LoadJWKSFile reads a JWKS from a JSON file
+func LoadJWKSFile(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+// ParseJWKS decodes a JWKS document and keeps the keys that can verify signatures. An identity
+// provider's set often also holds encryption keys or key types this package does not support;
+// those are left out, and parsing fails only when no usable key remains.
+func ParseJWKS(data []byte) (*JWKS, error) {
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.New("jwks has no keys")
+	}
+
+	usable := &JWKS{Keys: []JWK{}}
+	var skipped []error
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			skipped = append(skipped, fmt.Errorf("jwk %s: not a signing key", key.Kid))
+			continue
+		}
+		if _, err := key.verificationKey(); err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		usable.Keys = append(usable.Keys, key)
+	}
+	if len(usable.Keys) == 0 {
+		return nil, fmt.Errorf("jwks has no usable keys: %w", errors.Join(skipped...))
+	}
+	return usable, nil
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(segment)
+}
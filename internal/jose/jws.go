@@ -0,0 +1,219 @@
+package jose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// Signature algorithms
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	HS256 = "HS256"
+)
+
+// Algorithms lists every supported algorithm
+var Algorithms = []string{RS256, ES256, HS256}
+
+var (
+	// ErrMalformed reports a token that is not a compact JWS
+	ErrMalformed = errors.New("malformed token")
+	// ErrKeyNotFound reports that no key in the set can verify the token
+	ErrKeyNotFound = errors.New("no matching key")
+	// ErrSignature reports a signature that does not verify
+	ErrSignature = errors.New("invalid signature")
+)
+
+// Header is the protected header of a JWS
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Sign serializes claims as a compact JWS signed with key: an *rsa.PrivateKey for RS256, an
+// *ecdsa.PrivateKey on P-256 for ES256 or a []byte secret for HS256
+func Sign(header Header, claims any, key any) (string, error) {
+	if header.Typ == "" {
+		header.Typ = "JWT"
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("encode header: %w", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("encode claims: %w", err)
+	}
+	signingInput := encodeSegment(headerJSON) + "." + encodeSegment(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch header.Alg {
+	case RS256:
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("RS256 needs an RSA private key, got %T", key)
+		}
+		signature, err = rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	case ES256:
+		privateKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("ES256 needs an ECDSA private key, got %T", key)
+		}
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, privateKey, digest[:])
+		if err == nil {
+			// JWS uses the fixed-size R || S form rather than ASN.1
+			signature = make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+		}
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return "", fmt.Errorf("HS256 needs a []byte secret, got %T", key)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+	if err != nil {
+		return "", fmt.Errorf("sign: %w", err)
+	}
+	return signingInput + "." + encodeSegment(signature), nil
+}
+
+// Token is a parsed compact JWS whose signature has not been checked yet
+type Token struct {
+	Header Header
+	// Payload is the decoded payload, normally JSON claims
+	Payload      []byte
+	signingInput string
+	signature    []byte
+}
+
+// Parse splits a compact JWS into its parts without verifying it
+func Parse(token string) (*Token, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	headerJSON, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformed, err)
+	}
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrMalformed, err)
+	}
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrMalformed, err)
+	}
+
+	var header Header
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformed, err)
+	}
+	return &Token{
+		Header:       header,
+		Payload:      payload,
+		signingInput: parts[0] + "." + parts[1],
+		signature:    signature,
+	}, nil
+}
+
+// Claims decodes the payload into v
+func (t *Token) Claims(v any) error {
+	if err := json.Unmarshal(t.Payload, v); err != nil {
+		return fmt.Errorf("%w: claims: %v", ErrMalformed, err)
+	}
+	return nil
+}
+
+// VerifyWith checks the signature against the keys of set that fit the token: the key named by
+// the token's kid, or every key of the right type when the token names none. The algorithm has
+// to be one of algorithms and match the key's type, so an RSA public key can never be used as
+// an HMAC secret.
+func (t *Token) VerifyWith(set *JWKS, algorithms []string) error {
+	if !slices.Contains(algorithms, t.Header.Alg) {
+		return fmt.Errorf("algorithm %q is not accepted", t.Header.Alg)
+	}
+
+	tried := false
+	for _, jwk := range set.Keys {
+		if t.Header.Kid != "" && jwk.Kid != t.Header.Kid {
+			continue
+		}
+		if !jwk.fits(t.Header.Alg) {
+			continue
+		}
+		key, err := jwk.verificationKey()
+		if err != nil {
+			continue
+		}
+		tried = true
+		if t.verify(key) {
+			return nil
+		}
+	}
+	if !tried {
+		return ErrKeyNotFound
+	}
+	return ErrSignature
+}
+
+// fits reports whether the key may verify alg
+func (k JWK) fits(alg string) bool {
+	if k.Alg != "" && k.Alg != alg {
+		return false
+	}
+	if k.Use != "" && k.Use != "sig" {
+		return false
+	}
+	switch alg {
+	case RS256:
+		return k.Kty == KeyTypeRSA
+	case ES256:
+		return k.Kty == KeyTypeEC
+	case HS256:
+		return k.Kty == KeyTypeOct
+	default:
+		return false
+	}
+}
+
+// verify checks the signature with a key whose type already matches the algorithm
+func (t *Token) verify(key any) bool {
+	digest := sha256.Sum256([]byte(t.signingInput))
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], t.signature) == nil
+	case *ecdsa.PublicKey:
+		if len(t.signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(t.signature[:32])
+		s := new(big.Int).SetBytes(t.signature[32:])
+		return ecdsa.Verify(key, digest[:], r, s)
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(t.signingInput))
+		return hmac.Equal(mac.Sum(nil), t.signature)
+	default:
+		return false
+	}
+}
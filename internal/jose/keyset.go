@@ -0,0 +1,176 @@
+package jose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// remoteCacheTTL is how long a fetched JWKS is used before it is fetched again
+	remoteCacheTTL = 15 * time.Minute
+	// remoteMinRefresh limits how often an unknown kid may force a fetch, so tokens with made
+	// up key IDs cannot turn the verifier against the JWKS endpoint
+	remoteMinRefresh = 30 * time.Second
+	// maxJWKSSize caps the size of a fetched JWKS document
+	maxJWKSSize = 1 << 20
+)
+
+// KeySet supplies the keys tokens are verified with
+type KeySet interface {
+	// Keys returns the current keys. refresh asks for a fresh copy after a token named a key
+	// the previous copy did not have; sets that cannot refresh ignore it.
+	Keys(ctx context.Context, refresh bool) (*JWKS, error)
+}
+
+// StaticKeySet is a KeySet that never changes, such as one read from a file at startup
+type StaticKeySet struct {
+	Set *JWKS
+}
+
+// Keys returns the fixed set
+func (s StaticKeySet) Keys(context.Context, bool) (*JWKS, error) {
+	return s.Set, nil
+}
+
+// RemoteKeySet fetches a JWKS from an endpoint and caches it, fetching again when the cache
+// expires or a token names a key it does not know
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	cached  *JWKS
+	fetched time.Time
+	// inflight is the fetch in progress, if any; callers that need fresh keys share it
+	inflight *jwksFetch
+}
+
+// jwksFetch is one fetch of a remote JWKS; set and err are valid once done is closed
+type jwksFetch struct {
+	done chan struct{}
+	set  *JWKS
+	err  error
+}
+
+// NewRemoteKeySet creates a key set backed by the JWKS at url. A nil client uses a client
+// with a 10 second timeout.
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteKeySet{url: url, client: client}
+}
+
+// Keys returns the cached set, fetching it when needed. The fetch runs without the lock held,
+// and concurrent callers wait for the same fetch rather than each starting their own.
+func (r *RemoteKeySet) Keys(ctx context.Context, refresh bool) (*JWKS, error) {
+	r.mu.Lock()
+	age := time.Since(r.fetched)
+	cached := r.cached
+	if cached != nil && age < remoteCacheTTL && (!refresh || age < remoteMinRefresh) {
+		r.mu.Unlock()
+		return cached, nil
+	}
+	call := r.inflight
+	if call == nil {
+		call = &jwksFetch{done: make(chan struct{})}
+		r.inflight = call
+		// The fetch serves every waiting caller, so one giving up must not cut it short
+		go r.refresh(context.WithoutCancel(ctx), call)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		if cached != nil {
+			// Keep verifying with the keys we have while the endpoint is unavailable
+			return cached, nil
+		}
+		return nil, call.err
+	}
+	return call.set, nil
+}
+
+// refresh runs a fetch and caches its result if it succeeded
+func (r *RemoteKeySet) refresh(ctx context.Context, call *jwksFetch) {
+	call.set, call.err = r.fetch(ctx)
+
+	r.mu.Lock()
+	if call.err == nil {
+		r.cached, r.fetched = call.set, time.Now()
+	}
+	r.inflight = nil
+	r.mu.Unlock()
+	close(call.done)
+}
+
+func (r *RemoteKeySet) fetch(ctx context.Context) (*JWKS, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create jwks request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return nil, fmt.Errorf("read jwks: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+// LoadKeySet opens the JWKS at source, which is either an http(s) URL, fetched lazily and
+// kept fresh, or a file path, read once
+func LoadKeySet(source string) (KeySet, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return NewRemoteKeySet(source, nil), nil
+	}
+	set, err := LoadJWKSFile(source)
+	if err != nil {
+		return nil, err
+	}
+	return StaticKeySet{Set: set}, nil
+}
+
+// Verify parses token and checks its signature with keys, accepting only algorithms. A token
+// naming a key the set does not know makes the set refresh once.
+func Verify(ctx context.Context, token string, keys KeySet, algorithms []string) (*Token, error) {
+	parsed, err := Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	set, err := keys.Keys(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	err = parsed.VerifyWith(set, algorithms)
+	if errors.Is(err, ErrKeyNotFound) {
+		if set, err = keys.Keys(ctx, true); err != nil {
+			return nil, err
+		}
+		err = parsed.VerifyWith(set, algorithms)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
@@ -0,0 +1,96 @@
+package jose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LoadPrivateKeyFile reads an RSA or P-256 private key from a PEM file
+func LoadPrivateKeyFile(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	return ParsePrivateKeyPEM(data)
+}
+
+// ParsePrivateKeyPEM decodes an RSA or P-256 private key in PKCS #8, PKCS #1 or SEC 1 form
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key: no PEM block found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	if _, err := SigningAlgorithm(key); err != nil {
+		return nil, err
+	}
+	return key.(crypto.Signer), nil
+}
+
+// SigningAlgorithm returns the algorithm Sign uses with a private key: RS256 for RSA keys and
+// ES256 for P-256 keys
+func SigningAlgorithm(key any) (string, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return RS256, nil
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return "", fmt.Errorf("unsupported curve %s", key.Curve.Params().Name)
+		}
+		return ES256, nil
+	default:
+		return "", fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// Thumbprint computes the RFC 7638 thumbprint of a public key, base64url encoded. It makes a
+// stable key ID for keys that have no other name.
+func Thumbprint(jwk JWK) (string, error) {
+	// The members are the required ones for the key type, in lexicographic order
+	var members any
+	switch jwk.Kty {
+	case KeyTypeRSA:
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case KeyTypeEC:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		return "", fmt.Errorf("thumbprint: unsupported key type %q", jwk.Kty)
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(data)
+	return encodeSegment(digest[:]), nil
+}
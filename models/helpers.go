@@ -7,3 +7,7 @@ func StringPtr(s string) *string {
 func BoolPtr(b bool) *bool {
 	return &b
 }
+
+func IntPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Part kinds, used as the value of a part's "type" field
+const (
+	PartTypeText = "text"
+	PartTypeFile = "file"
+	PartTypeData = "data"
+)
+
+// partJSON is the wire form of a Part
+type partJSON struct {
+	Type     *string                 `json:"type,omitempty"`
+	Text     *string                 `json:"text,omitempty"`
+	File     json.RawMessage         `json:"file,omitempty"`
+	Data     *map[string]interface{} `json:"data,omitempty"`
+	Metadata map[string]interface{}  `json:"metadata,omitempty"`
+}
+
+// fileJSON is the wire form of FileContent; exactly one of Bytes and URI is set
+type fileJSON struct {
+	Name     *string `json:"name,omitempty"`
+	MimeType *string `json:"mimeType,omitempty"`
+	Bytes    *string `json:"bytes,omitempty"`
+	URI      *string `json:"uri,omitempty"`
+}
+
+// kind reports which kind of content the part holds, failing unless there is exactly one
+func (p Part) kind() (string, error) {
+	var kinds []string
+	if p.Text != nil {
+		kinds = append(kinds, PartTypeText)
+	}
+	if p.File != nil {
+		kinds = append(kinds, PartTypeFile)
+	}
+	if p.Data != nil {
+		kinds = append(kinds, PartTypeData)
+	}
+	switch len(kinds) {
+	case 0:
+		return "", errors.New("part has no text, file or data")
+	case 1:
+		return kinds[0], nil
+	default:
+		return "", fmt.Errorf("part has more than one kind of content: %v", kinds)
+	}
+}
+
+// Validate checks that the part holds exactly one kind of content, matching its Type if set
+func (p Part) Validate() error {
+	kind, err := p.kind()
+	if err != nil {
+		return err
+	}
+	if p.Type != nil && *p.Type != kind {
+		return fmt.Errorf("part has type %q but holds %s content", *p.Type, kind)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the part with its "type" set to the kind of content it holds. It does not
+// validate the part, so that a response is never cut short by encoding; parts received from
+// clients are checked by UnmarshalJSON and Validate instead. Data is written whenever it is
+// non-nil, even when empty.
+func (p Part) MarshalJSON() ([]byte, error) {
+	wire := partJSON{Type: p.Type, Text: p.Text, Metadata: p.Metadata}
+	if kind, err := p.kind(); err == nil {
+		wire.Type = &kind
+	}
+	if p.Data != nil {
+		wire.Data = &p.Data
+	}
+	if p.File != nil {
+		file, err := json.Marshal(p.File)
+		if err != nil {
+			return nil, err
+		}
+		wire.File = file
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON decodes a text, file or data part. The part must hold exactly one kind of
+// content, matching its "type" if one is given; Type is set to that kind. File content becomes
+// FileContentBytes or FileContentURI depending on which of "bytes" and "uri" is present.
+func (p *Part) UnmarshalJSON(data []byte) error {
+	var wire partJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	part := Part{Text: wire.Text, Metadata: wire.Metadata}
+	if wire.Data != nil {
+		part.Data = *wire.Data
+	}
+	if len(wire.File) > 0 && string(wire.File) != "null" {
+		file, err := unmarshalFileContent(wire.File)
+		if err != nil {
+			return err
+		}
+		part.File = file
+	}
+
+	kind, err := part.kind()
+	if err != nil {
+		return err
+	}
+	if wire.Type != nil && *wire.Type != kind {
+		return fmt.Errorf("part has type %q but holds %s content", *wire.Type, kind)
+	}
+	part.Type = &kind
+
+	*p = part
+	return nil
+}
+
+// unmarshalFileContent decodes file content given either as base64 bytes or as a URI
+func unmarshalFileContent(data []byte) (FileContent, error) {
+	var wire fileJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return nil, fmt.Errorf("file part: %w", err)
+	}
+	base := FileContentBase{Name: wire.Name, MimeType: wire.MimeType}
+
+	switch {
+	case wire.Bytes != nil && wire.URI != nil:
+		return nil, errors.New("file part has both bytes and uri")
+	case wire.Bytes != nil:
+		if _, err := base64.StdEncoding.DecodeString(*wire.Bytes); err != nil {
+			return nil, fmt.Errorf("file part: bytes are not valid base64: %w", err)
+		}
+		return FileContentBytes{FileContentBase: base, Bytes: *wire.Bytes}, nil
+	case wire.URI != nil:
+		if *wire.URI == "" {
+			return nil, errors.New("file part has an empty uri")
+		}
+		return FileContentURI{FileContentBase: base, URI: *wire.URI}, nil
+	default:
+		return nil, errors.New("file part has neither bytes nor uri")
+	}
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPart_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		part Part
+		kind string
+		json string
+	}{
+		{
+			name: "text",
+			part: Part{Text: StringPtr("hello"), Metadata: map[string]interface{}{"lang": "en"}},
+			kind: PartTypeText,
+			json: `{"type":"text","text":"hello","metadata":{"lang":"en"}}`,
+		},
+		{
+			name: "file bytes",
+			part: Part{File: FileContentBytes{
+				FileContentBase: FileContentBase{Name: StringPtr("receipt.txt"), MimeType: StringPtr("text/plain")},
+				Bytes:           "aGVsbG8=",
+			}},
+			kind: PartTypeFile,
+			json: `{"type":"file","file":{"name":"receipt.txt","mimeType":"text/plain","bytes":"aGVsbG8="}}`,
+		},
+		{
+			name: "file uri",
+			part: Part{File: FileContentURI{URI: "https://example.com/receipt.pdf"}},
+			kind: PartTypeFile,
+			json: `{"type":"file","file":{"uri":"https://example.com/receipt.pdf"}}`,
+		},
+		{
+			name: "data",
+			part: Part{Data: map[string]interface{}{"amount": 15500.0, "currency": "TWD"}},
+			kind: PartTypeData,
+			json: `{"type":"data","data":{"amount":15500,"currency":"TWD"}}`,
+		},
+		{
+			name: "empty data",
+			part: Part{Data: map[string]interface{}{}},
+			kind: PartTypeData,
+			json: `{"type":"data","data":{}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := json.Marshal(tt.part)
+			if err != nil {
+				t.Fatalf("Failed to marshal part: %v", err)
+			}
+			if string(encoded) != tt.json {
+				t.Errorf("Expected %s, got %s", tt.json, encoded)
+			}
+
+			var decoded Part
+			if err := json.Unmarshal(encoded, &decoded); err != nil {
+				t.Fatalf("Failed to unmarshal part: %v", err)
+			}
+			want := tt.part
+			want.Type = StringPtr(tt.kind)
+			if !reflect.DeepEqual(decoded, want) {
+				t.Errorf("Expected %+v after round trip, got %+v", want, decoded)
+			}
+		})
+	}
+}
+
+func TestPart_UnmarshalWithoutType(t *testing.T) {
+	var message Message
+	if err := json.Unmarshal([]byte(`{"role":"user","parts":[{"text":"hi"},{"file":{"uri":"file:///tmp/a"}}]}`), &message); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	if *message.Parts[0].Type != PartTypeText || *message.Parts[1].Type != PartTypeFile {
+		t.Errorf("Expected text and file parts, got %v and %v", *message.Parts[0].Type, *message.Parts[1].Type)
+	}
+	if _, ok := message.Parts[1].File.(FileContentURI); !ok {
+		t.Errorf("Expected FileContentURI, got %T", message.Parts[1].File)
+	}
+}
+
+func TestPart_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"empty", `{}`, "no text, file or data"},
+		{"text and data", `{"text":"hi","data":{"a":1}}`, "more than one kind"},
+		{"type mismatch", `{"type":"data","text":"hi"}`, `type "data"`},
+		{"bytes and uri", `{"file":{"bytes":"aGk=","uri":"https://example.com"}}`, "both bytes and uri"},
+		{"no file content", `{"file":{"name":"a.txt"}}`, "neither bytes nor uri"},
+		{"bad base64", `{"file":{"bytes":"not base64!"}}`, "not valid base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var part Part
+			err := json.Unmarshal([]byte(tt.json), &part)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	invalid := []Part{
+		{},
+		{Text: StringPtr("hi"), Data: map[string]interface{}{"a": 1}},
+		{Type: StringPtr(PartTypeData), Text: StringPtr("hi")},
+	}
+	for _, part := range invalid {
+		if err := part.Validate(); err == nil {
+			t.Errorf("Expected %+v to fail validation", part)
+		}
+		// Encoding never fails on content, so a response is not cut short
+		if _, err := json.Marshal(part); err != nil {
+			t.Errorf("Expected %+v to encode, got %v", part, err)
+		}
+	}
+}
@@ -12,6 +12,8 @@ type TaskSendParams struct {
 	PushNotification *PushNotificationConfig `json:"pushNotification,omitempty"`
 	// HistoryLength is an optional parameter to specify how much message history to include
 	HistoryLength *int `json:"historyLength,omitempty"`
+	// Blocking selects whether message/send waits for the agent to finish. If omitted the agent's default applies.
+	Blocking *bool `json:"blocking,omitempty"`
 	// Metadata is optional metadata associated with sending this message
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
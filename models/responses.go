@@ -59,13 +59,13 @@ type GetTaskHistoryResponse struct {
 // SetTaskPushNotificationResponse represents a response to a set task push notification request
 type SetTaskPushNotificationResponse struct {
 	JSONRPCResponse
-	Result *PushNotificationConfig `json:"result,omitempty"`
-	Error  *A2AError               `json:"error,omitempty"`
+	Result *TaskPushNotificationConfig `json:"result,omitempty"`
+	Error  *A2AError                   `json:"error,omitempty"`
 }
 
 // GetTaskPushNotificationResponse represents a response to a get task push notification request
 type GetTaskPushNotificationResponse struct {
 	JSONRPCResponse
-	Result *PushNotificationConfig `json:"result,omitempty"`
-	Error  *A2AError               `json:"error,omitempty"`
+	Result *TaskPushNotificationConfig `json:"result,omitempty"`
+	Error  *A2AError                   `json:"error,omitempty"`
 }
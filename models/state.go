@@ -0,0 +1,29 @@
+package models
+
+// taskTransitions lists the states each state may move to. A task that does not exist yet
+// starts from the empty state.
+var taskTransitions = map[TaskState][]TaskState{
+	"":                     {TaskStateSubmitted, TaskStateWorking},
+	TaskStateSubmitted:     {TaskStateSubmitted, TaskStateWorking, TaskStateCanceled, TaskStateFailed},
+	TaskStateWorking:       {TaskStateWorking, TaskStateInputRequired, TaskStateCompleted, TaskStateCanceled, TaskStateFailed},
+	TaskStateInputRequired: {TaskStateSubmitted, TaskStateWorking, TaskStateCanceled, TaskStateFailed},
+	TaskStateUnknown:       {TaskStateSubmitted, TaskStateWorking, TaskStateInputRequired, TaskStateCompleted, TaskStateCanceled, TaskStateFailed},
+	TaskStateCompleted:     {},
+	TaskStateCanceled:      {},
+	TaskStateFailed:        {},
+}
+
+// IsTerminal reports whether a task in this state is finished for good
+func (s TaskState) IsTerminal() bool {
+	return s == TaskStateCompleted || s == TaskStateCanceled || s == TaskStateFailed
+}
+
+// CanTransitionTo reports whether a task may move from this state to next
+func (s TaskState) CanTransitionTo(next TaskState) bool {
+	for _, allowed := range taskTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
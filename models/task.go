@@ -64,14 +64,24 @@ type Artifact struct {
 
 // TaskStatus represents the status of a task
 type TaskStatus struct {
+	// State is the current state of the task
 	State TaskState `json:"state"`
+	// Message is the agent's reply accompanying this status, if any
+	Message *Message `json:"message,omitempty"`
+	// Timestamp is when the task entered this status, in ISO 8601 format
+	Timestamp string `json:"timestamp,omitempty"`
 }
 
 // Task represents an A2A task
 type Task struct {
-	ID       string                 `json:"id"`
-	Status   TaskStatus             `json:"status"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	ID     string     `json:"id"`
+	Status TaskStatus `json:"status"`
+	// StatusHistory lists every status the task has been in, oldest first. It is only
+	// kept by agents with the StateTransitionHistory capability.
+	StatusHistory []TaskStatus           `json:"statusHistory,omitempty"`
+	Artifacts     []Artifact             `json:"artifacts,omitempty"`
+	History       []Message              `json:"history,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // Message represents a message in the A2A protocol
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// DeliveryAdminPath is where a Host configured with WithAdmin reports push deliveries
+const DeliveryAdminPath = "/admin/deliveries"
+
+// WithAdmin serves DeliveryAdminPath, which reports each agent's push delivery metrics and dead
+// letters, to the requests authenticate accepts. Without it the route is not served.
+func WithAdmin(authenticate Authenticator) HostOption {
+	return func(h *Host) {
+		h.adminAuth = authenticate
+	}
+}
+
+// BearerAuthenticator accepts requests whose "Authorization: Bearer" token validate accepts
+func BearerAuthenticator(validate TokenValidator) Authenticator {
+	validators := AuthValidators{Bearer: validate}
+	return func(r *http.Request) (context.Context, error) {
+		principal, presented, err := validators.check(r, SchemeBearer)
+		if err != nil {
+			return nil, err
+		}
+		if !presented || principal == nil {
+			return nil, ErrUnauthorized
+		}
+		authenticated := *principal
+		authenticated.Scheme = SchemeBearer
+		return ContextWithPrincipal(r.Context(), &authenticated), nil
+	}
+}
+
+// AgentDeliveries is an agent's entry in the delivery report
+type AgentDeliveries struct {
+	Name        string          `json:"name"`
+	Path        string          `json:"path"`
+	Metrics     DeliveryMetrics `json:"metrics"`
+	DeadLetters []DeadLetter    `json:"deadLetters"`
+}
+
+// DeliveryReport is the document served at DeliveryAdminPath
+type DeliveryReport struct {
+	Agents []AgentDeliveries `json:"agents"`
+}
+
+// deliveryAdminHandler reports push deliveries on GET and discards a dead letter on
+// DELETE ?agent=<path>&id=<id>
+func (h *Host) deliveryAdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := h.adminAuth(r); err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			report := DeliveryReport{Agents: make([]AgentDeliveries, 0, len(h.servers))}
+			for _, mounted := range h.servers {
+				letters, err := mounted.server.DeadLetters()
+				if err != nil {
+					log.Printf("Error listing dead letters for %s: %v", mounted.prefix, err)
+					http.Error(w, "Failed to list dead letters", http.StatusInternalServerError)
+					return
+				}
+				report.Agents = append(report.Agents, AgentDeliveries{
+					Name:        mounted.server.agentCard.Name,
+					Path:        mounted.prefix,
+					Metrics:     mounted.server.DeliveryMetrics(),
+					DeadLetters: letters,
+				})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(report); err != nil {
+				log.Printf("Error encoding delivery report: %v", err)
+			}
+		case http.MethodDelete:
+			agent, id := r.URL.Query().Get("agent"), r.URL.Query().Get("id")
+			if agent == "" || id == "" {
+				http.Error(w, "agent and id are required", http.StatusBadRequest)
+				return
+			}
+			for _, mounted := range h.servers {
+				if mounted.prefix != "/"+strings.Trim(agent, "/") {
+					continue
+				}
+				err := mounted.server.DeleteDeadLetter(id)
+				switch {
+				case errors.Is(err, ErrDeadLetterNotFound):
+					http.Error(w, "Dead letter not found", http.StatusNotFound)
+				case err != nil:
+					log.Printf("Error deleting dead letter %s for %s: %v", id, mounted.prefix, err)
+					http.Error(w, "Failed to delete dead letter", http.StatusInternalServerError)
+				default:
+					w.WriteHeader(http.StatusNoContent)
+				}
+				return
+			}
+			http.Error(w, "Agent not found", http.StatusNotFound)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, DELETE")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
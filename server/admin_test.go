@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHost_DeliveryAdmin(t *testing.T) {
+	server := NewA2AServer(pushAgentCard(), mockTaskHandler)
+	_ = server.deadLetters.Add(DeadLetter{ID: "letter-1", TaskID: "task-1"})
+
+	host := NewHost("127.0.0.1:0", WithAdmin(BearerAuthenticator(StaticTokens(map[string]string{"admin-token": "admin"}))))
+	host.Handle("/agent/one", server)
+	handler := host.Handler()
+
+	do := func(method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		if w := do(http.MethodGet, DeliveryAdminPath, token); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d with token %q, got %d", http.StatusUnauthorized, token, w.Code)
+		}
+	}
+
+	w := do(http.MethodGet, DeliveryAdminPath, "admin-token")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var report DeliveryReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if len(report.Agents) != 1 || report.Agents[0].Path != "/agent/one" || len(report.Agents[0].DeadLetters) != 1 {
+		t.Fatalf("Expected the agent's dead letter, got %+v", report)
+	}
+
+	if w := do(http.MethodDelete, DeliveryAdminPath+"?agent=/agent/two&id=letter-1", "admin-token"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown agent, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := do(http.MethodDelete, DeliveryAdminPath+"?agent=agent/one&id=letter-1", "admin-token"); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if w := do(http.MethodDelete, DeliveryAdminPath+"?agent=/agent/one&id=letter-1", "admin-token"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a deleted letter, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// Without WithAdmin the route is not served
+	open := NewHost("127.0.0.1:0")
+	open.Handle("/agent/one", server)
+	w = httptest.NewRecorder()
+	open.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, DeliveryAdminPath, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d without WithAdmin, got %d", http.StatusNotFound, w.Code)
+	}
+}
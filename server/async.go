@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"a2a/models"
+)
+
+const (
+	// defaultWorkers is how many queued messages are processed at once
+	defaultWorkers = 4
+	// defaultQueueSize is how many messages may wait for a worker before sends are rejected
+	defaultQueueSize = 100
+)
+
+// errQueueFull is returned when a non-blocking send cannot be queued
+var errQueueFull = errors.New("task queue is full, try again later")
+
+// sendJob is a message accepted by a non-blocking message/send, waiting for a worker
+type sendJob struct {
+	ctx    context.Context
+	params models.TaskSendParams
+	// unlock releases the task lock, which a worker takes when it picks the job
+	unlock func()
+}
+
+// isBlocking reports whether message/send should wait for the handler to finish.
+// The per-call setting wins over the server default.
+func (s *A2AServer) isBlocking(params *models.TaskSendParams) bool {
+	if params.Blocking != nil {
+		return *params.Blocking
+	}
+	return !s.asyncSend
+}
+
+// processMessage runs the handler for a message to completion and returns the stored task
+func (s *A2AServer) processMessage(ctx context.Context, params *models.TaskSendParams) (*models.Task, error) {
+	// Messages to the same task are handled one at a time, in order; other tasks run in parallel
+	unlock, err := s.taskLocks.lock(ctx, params.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return s.runMessage(ctx, params)
+}
+
+// runMessage runs the handler for a message, with the task lock already held
+func (s *A2AServer) runMessage(ctx context.Context, params *models.TaskSendParams) (*models.Task, error) {
+	// Record events so the run can be followed with tasks/resubscribe while it is in flight
+	log := s.startEventLog(params.ID)
+	defer s.endEventLog(params.ID, log)
+
+	task, ctx, done, err := s.beginTask(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("store task: %w", err)
+	}
+	defer done()
+
+	return s.runTask(ctx, log, task, &params.Message)
+}
+
+// enqueueMessage stores the task as submitted and hands the message to the worker pool.
+// A task that is already being worked on keeps its current state until its turn comes.
+func (s *A2AServer) enqueueMessage(ctx context.Context, params models.TaskSendParams) (*models.Task, error) {
+	// Hold s.mu so a worker cannot mark the task working before it is stored as submitted,
+	// and so Shutdown cannot close the queue while the message is being added
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing.Load() {
+		return nil, errShuttingDown
+	}
+	if len(s.queue) >= s.queueSize {
+		return nil, errQueueFull
+	}
+	s.startWorkers.Do(func() {
+		s.workerGroup.Add(s.workers)
+		for i := 0; i < s.workers; i++ {
+			go s.worker()
+		}
+	})
+
+	previous, err := s.store.Get(params.ID)
+	if err != nil && !errors.Is(err, ErrTaskNotFound) {
+		return nil, fmt.Errorf("load task: %w", err)
+	}
+
+	task := &models.Task{ID: params.ID}
+	if previous != nil {
+		submitted := *previous
+		task = &submitted
+	}
+	if !s.isRunning(params.ID) {
+		if err := checkTransition(task.ID, task.Status.State, models.TaskStateSubmitted); err != nil {
+			return nil, err
+		}
+		s.setStatus(task, models.TaskStatus{State: models.TaskStateSubmitted})
+		if err := s.store.Put(task); err != nil {
+			return nil, fmt.Errorf("store task: %w", err)
+		}
+	}
+
+	// The job outlives this request, but keeps its values
+	s.queue = append(s.queue, sendJob{ctx: context.WithoutCancel(ctx), params: params})
+	s.queueChanged.Broadcast()
+
+	if task.Status.State == models.TaskStateSubmitted {
+		s.sendPushNotification(task.ID, models.TaskStatusUpdateEvent{
+			ID:     task.ID,
+			Status: task.Status,
+			Final:  boolPtr(false),
+		})
+	}
+	return task, nil
+}
+
+// worker processes queued messages until the server shuts down and the queue is empty
+func (s *A2AServer) worker() {
+	defer s.workerGroup.Done()
+	for {
+		job, ok := s.nextJob()
+		if !ok {
+			return
+		}
+		s.processJob(job)
+	}
+}
+
+// nextJob waits for the oldest queued message whose task is free and takes the task's lock
+// for it. Messages to a busy task stay queued without holding a worker, so the other tasks
+// keep running, and a task's messages are still picked in the order they came in. It reports
+// false once the server is shutting down and the queue is empty.
+func (s *A2AServer) nextJob() (sendJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		for i, job := range s.queue {
+			if unlock, ok := s.taskLocks.tryLock(job.params.ID); ok {
+				s.queue = slices.Delete(s.queue, i, i+1)
+				job.unlock = unlock
+				return job, true
+			}
+		}
+		if len(s.queue) == 0 && s.closing.Load() {
+			return sendJob{}, false
+		}
+		// Woken by a new message, a task lock being released, or Shutdown
+		s.queueChanged.Wait()
+	}
+}
+
+// wakeWorkers has the workers look at the queue again
+func (s *A2AServer) wakeWorkers() {
+	s.mu.Lock()
+	s.queueChanged.Broadcast()
+	s.mu.Unlock()
+}
+
+// processJob runs one queued message. A panic is logged rather than allowed to take the
+// whole process down with it.
+func (s *A2AServer) processJob(job sendJob) {
+	defer job.unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Printf("Recovered from panic in queued task %s: %v", job.params.ID, r)
+		}
+	}()
+	if _, err := s.runMessage(job.ctx, &job.params); err != nil {
+		s.logger.Printf("Queued task %s failed: %v", job.params.ID, err)
+	}
+}
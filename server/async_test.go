@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+// gatedHandler completes tasks once release is closed, signaling each start on started
+func gatedHandler(started chan<- string, release <-chan struct{}) TaskHandler {
+	return func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		started <- task.ID
+		<-release
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+}
+
+// getState fetches a task's current state through tasks/get
+func getState(t *testing.T, server *A2AServer, taskID string) models.TaskState {
+	t.Helper()
+
+	response := doRPC(t, server, "tasks/get", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: taskID}})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	var task models.Task
+	decodeResult(t, response.Result, &task)
+	return task.Status.State
+}
+
+// waitForState polls tasks/get until the task reaches the wanted state
+func waitForState(t *testing.T, server *A2AServer, taskID string, want models.TaskState) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if getState(t, server, taskID) == want {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for task %s to become %s", taskID, want)
+}
+
+func TestA2AServer_AsyncSend(t *testing.T) {
+	started, release := make(chan string, 1), make(chan struct{})
+	server := NewA2AServer(mockAgentCard, gatedHandler(started, release), WithAsyncSend(true))
+
+	response := doRPC(t, server, "message/send", sendParams("test-task-1", "Hello"))
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	var task models.Task
+	decodeResult(t, response.Result, &task)
+	if task.Status.State != models.TaskStateSubmitted {
+		t.Errorf("Expected immediate %s response, got %s", models.TaskStateSubmitted, task.Status.State)
+	}
+
+	<-started
+	if state := getState(t, server, "test-task-1"); state != models.TaskStateWorking {
+		t.Errorf("Expected %s while the handler runs, got %s", models.TaskStateWorking, state)
+	}
+
+	close(release)
+	waitForState(t, server, "test-task-1", models.TaskStateCompleted)
+}
+
+func TestA2AServer_PerCallBlocking(t *testing.T) {
+	started, release := make(chan string, 2), make(chan struct{})
+	close(release)
+
+	// A blocking server can be asked not to block
+	server := NewA2AServer(mockAgentCard, gatedHandler(started, release))
+	params := sendParams("test-task-1", "Hello")
+	params.Blocking = boolPtr(false)
+	var task models.Task
+	decodeResult(t, doRPC(t, server, "message/send", params).Result, &task)
+	if task.Status.State != models.TaskStateSubmitted {
+		t.Errorf("Expected %s for a non-blocking call, got %s", models.TaskStateSubmitted, task.Status.State)
+	}
+	waitForState(t, server, "test-task-1", models.TaskStateCompleted)
+
+	// ...and an async server can be asked to block
+	server = NewA2AServer(mockAgentCard, gatedHandler(started, release), WithAsyncSend(true))
+	params.Blocking = boolPtr(true)
+	decodeResult(t, doRPC(t, server, "message/send", params).Result, &task)
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected %s for a blocking call, got %s", models.TaskStateCompleted, task.Status.State)
+	}
+}
+
+func TestA2AServer_AsyncQueueFull(t *testing.T) {
+	started, release := make(chan string, 3), make(chan struct{})
+	defer close(release)
+	server := NewA2AServer(mockAgentCard, gatedHandler(started, release), WithAsyncSend(true), WithWorkerPool(1, 1))
+
+	// One message occupies the only worker, the next fills the queue
+	doRPC(t, server, "message/send", sendParams("task-1", "Hello"))
+	<-started
+	if response := doRPC(t, server, "message/send", sendParams("task-2", "Hello")); response.Error != nil {
+		t.Fatalf("Expected second message to be queued, got %v", response.Error)
+	}
+
+	response := doRPC(t, server, "message/send", sendParams("task-3", "Hello"))
+	if response.Error == nil {
+		t.Fatal("Expected a full queue to reject the message")
+	}
+	if _, err := server.store.Get("task-3"); err == nil {
+		t.Error("Expected rejected task not to be stored")
+	}
+}
+
+func TestA2AServer_AsyncBusyTaskKeepsWorkersFree(t *testing.T) {
+	started, release := make(chan string, 4), make(chan struct{})
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		started <- task.ID
+		if task.ID == "task-a" {
+			<-release
+		}
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler, WithAsyncSend(true), WithWorkerPool(1, 10))
+
+	// A blocking call keeps task-a busy without using a worker
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		params := sendParams("task-a", "first")
+		params.Blocking = boolPtr(true)
+		doRPC(t, server, "message/send", params)
+	}()
+	if id := <-started; id != "task-a" {
+		t.Fatalf("Expected task-a to start, got %s", id)
+	}
+
+	// Follow-ups to task-a wait for it without holding the only worker, so task-b still runs
+	for _, params := range []models.TaskSendParams{sendParams("task-a", "second"), sendParams("task-a", "third"), sendParams("task-b", "hello")} {
+		if response := doRPC(t, server, "message/send", params); response.Error != nil {
+			t.Fatalf("Expected the message to be queued, got %v", response.Error)
+		}
+	}
+	select {
+	case id := <-started:
+		if id != "task-b" {
+			t.Fatalf("Expected task-b to start, got %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected task-b to run while task-a is busy")
+	}
+
+	close(release)
+	<-blocked
+	for range 2 {
+		if id := <-started; id != "task-a" {
+			t.Errorf("Expected the task-a follow-ups next, got %s", id)
+		}
+	}
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	history, _ := server.store.History("task-a")
+	var texts []string
+	for _, message := range history {
+		if message.Role == "user" {
+			texts = append(texts, *message.Parts[0].Text)
+		}
+	}
+	if strings.Join(texts, ",") != "first,second,third" {
+		t.Errorf("Expected task-a messages in order, got %v", texts)
+	}
+}
+
+func TestA2AServer_AsyncFollowUpWhileWorking(t *testing.T) {
+	started, release := make(chan string, 2), make(chan struct{})
+	server := NewA2AServer(mockAgentCard, func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		started <- task.ID
+		<-release
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}, WithAsyncSend(true))
+
+	// The run is registered by the time the task is stored as working
+	task, _, done, err := server.beginTask(context.Background(), &models.TaskSendParams{ID: "test-task-0"})
+	if err != nil {
+		t.Fatalf("Failed to begin task: %v", err)
+	}
+	if task.Status.State != models.TaskStateWorking || !server.isRunning(task.ID) {
+		t.Errorf("Expected a working task with a registered run, got %s running=%v", task.Status.State, server.isRunning(task.ID))
+	}
+	done()
+
+	doRPC(t, server, "message/send", sendParams("test-task-1", "Hello"))
+	<-started
+
+	// A follow-up queued while the first message is handled leaves the task working
+	response := doRPC(t, server, "message/send", sendParams("test-task-1", "More"))
+	if response.Error != nil {
+		t.Fatalf("Expected follow-up to be queued, got %v", response.Error)
+	}
+	var queued models.Task
+	decodeResult(t, response.Result, &queued)
+	if queued.Status.State != models.TaskStateWorking {
+		t.Errorf("Expected %s while the first message runs, got %s", models.TaskStateWorking, queued.Status.State)
+	}
+
+	close(release)
+	<-started
+	waitForState(t, server, "test-task-1", models.TaskStateInputRequired)
+}
+
+func TestA2AServer_AsyncHandlerWithoutTask(t *testing.T) {
+	handlers := map[string]TaskHandler{
+		"nil task": func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+			return nil, nil
+		},
+		"panic": func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+			panic("boom")
+		},
+	}
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			server := NewA2AServer(mockAgentCard, handler, WithAsyncSend(true))
+
+			// The worker survives and the task fails instead of staying working
+			doRPC(t, server, "message/send", sendParams("test-task-1", "Hello"))
+			waitForState(t, server, "test-task-1", models.TaskStateFailed)
+
+			response := doRPC(t, server, "message/send", models.TaskSendParams{
+				ID:       "test-task-2",
+				Message:  models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+				Blocking: boolPtr(true),
+			})
+			if response.Error == nil {
+				t.Error("Expected a blocking send to report the handler's failure")
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"a2a/models"
+)
+
+// Authenticator vets a JSON-RPC request before it is read. It returns the context the request
+// continues with, which can carry the caller's identity to the handler, or an error to refuse
+// it with 401 Unauthorized. A *Error is sent as it is; any other error is reported as
+// "Unauthorized" without its details.
+type Authenticator func(r *http.Request) (context.Context, error)
+
+// ErrUnauthorized is the error sent to callers an Authenticator refuses
+var ErrUnauthorized = NewError(models.ErrorCodeInvalidRequest, "Unauthorized")
+
+// authError picks the error to report for a refused request
+func authError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	return ErrUnauthorized
+}
+
+// Authentication schemes an agent card can declare. Names are matched case-insensitively.
+const (
+	// SchemeBearer takes a token in an "Authorization: Bearer" header
+	SchemeBearer = "bearer"
+	// SchemeAPIKey takes a key in a request header, X-API-Key unless configured otherwise
+	SchemeAPIKey = "apiKey"
+	// SchemeBasic takes a username and password in an "Authorization: Basic" header
+	SchemeBasic = "basic"
+)
+
+// defaultAPIKeyHeader carries API keys unless AuthValidators names another header
+const defaultAPIKeyHeader = "X-API-Key"
+
+// Principal is the authenticated caller of a request, available to handlers through
+// PrincipalFromContext
+type Principal struct {
+	// Subject identifies the caller, such as a user or client ID
+	Subject string
+	// Scheme is the authentication scheme the caller used
+	Scheme string
+	// Claims holds further attributes of the caller, such as roles, as the validator saw them
+	Claims map[string]any
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying principal
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller authenticated for the request ctx belongs to
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// TokenValidator checks a bearer token or API key and returns the caller it belongs to
+type TokenValidator func(ctx context.Context, token string) (*Principal, error)
+
+// BasicValidator checks a username and password and returns the caller they belong to
+type BasicValidator func(ctx context.Context, username, password string) (*Principal, error)
+
+// AuthValidators checks credentials for the schemes an agent card declares. A declared scheme
+// without a validator refuses every request that uses it.
+type AuthValidators struct {
+	Bearer TokenValidator
+	APIKey TokenValidator
+	Basic  BasicValidator
+	// APIKeyHeader is the header API keys are read from (default X-API-Key)
+	APIKeyHeader string
+}
+
+// errInvalidCredentials is returned for credentials no validator accepts
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// StaticTokens accepts the given tokens, mapping each to the subject it authenticates.
+// Tokens are compared in constant time.
+func StaticTokens(tokens map[string]string) TokenValidator {
+	return func(ctx context.Context, token string) (*Principal, error) {
+		for known, subject := range tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+				return &Principal{Subject: subject}, nil
+			}
+		}
+		return nil, errInvalidCredentials
+	}
+}
+
+// StaticCredentials accepts the given usernames with their passwords. Passwords are compared
+// in constant time.
+func StaticCredentials(passwords map[string]string) BasicValidator {
+	return func(ctx context.Context, username, password string) (*Principal, error) {
+		known, exists := passwords[username]
+		if subtle.ConstantTimeCompare([]byte(password), []byte(known)) != 1 || !exists {
+			return nil, errInvalidCredentials
+		}
+		return &Principal{Subject: username}, nil
+	}
+}
+
+// cardAuthenticator enforces the schemes declared on the agent card, using validators to check
+// credentials. A card that declares no schemes leaves the agent open. Otherwise the request has
+// to carry valid credentials for one of the declared schemes.
+func (s *A2AServer) cardAuthenticator(validators AuthValidators) Authenticator {
+	return func(r *http.Request) (context.Context, error) {
+		schemes := s.authSchemes()
+		if len(schemes) == 0 {
+			return r.Context(), nil
+		}
+		for _, scheme := range schemes {
+			principal, presented, err := validators.check(r, scheme)
+			if !presented {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if principal == nil {
+				return nil, errInvalidCredentials
+			}
+			authenticated := *principal
+			authenticated.Scheme = scheme
+			return ContextWithPrincipal(r.Context(), &authenticated), nil
+		}
+		return nil, ErrUnauthorized
+	}
+}
+
+// authSchemes returns the schemes declared on the agent card, normalized to the Scheme constants
+func (s *A2AServer) authSchemes() []string {
+	if s.agentCard.Authentication == nil {
+		return nil
+	}
+	schemes := make([]string, 0, len(s.agentCard.Authentication.Schemes))
+	for _, scheme := range s.agentCard.Authentication.Schemes {
+		for _, known := range []string{SchemeBearer, SchemeAPIKey, SchemeBasic} {
+			if strings.EqualFold(scheme, known) {
+				scheme = known
+			}
+		}
+		schemes = append(schemes, scheme)
+	}
+	return schemes
+}
+
+// check validates the credentials r carries for scheme, reporting whether it carries any
+func (v AuthValidators) check(r *http.Request, scheme string) (*Principal, bool, error) {
+	switch scheme {
+	case SchemeBearer:
+		kind, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !found || !strings.EqualFold(kind, "Bearer") {
+			return nil, false, nil
+		}
+		if v.Bearer == nil {
+			return nil, true, errInvalidCredentials
+		}
+		principal, err := v.Bearer(r.Context(), strings.TrimSpace(token))
+		return principal, true, err
+	case SchemeAPIKey:
+		header := v.APIKeyHeader
+		if header == "" {
+			header = defaultAPIKeyHeader
+		}
+		key := r.Header.Get(header)
+		if key == "" {
+			return nil, false, nil
+		}
+		if v.APIKey == nil {
+			return nil, true, errInvalidCredentials
+		}
+		principal, err := v.APIKey(r.Context(), key)
+		return principal, true, err
+	case SchemeBasic:
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return nil, false, nil
+		}
+		if v.Basic == nil {
+			return nil, true, errInvalidCredentials
+		}
+		principal, err := v.Basic(r.Context(), username, password)
+		return principal, true, err
+	default:
+		return nil, false, nil
+	}
+}
+
+// authChallenges lists the WWW-Authenticate challenges for the schemes the card declares
+func (s *A2AServer) authChallenges() []string {
+	var challenges []string
+	for _, scheme := range s.authSchemes() {
+		switch scheme {
+		case SchemeBearer:
+			challenges = append(challenges, fmt.Sprintf("Bearer realm=%q", s.agentCard.Name))
+		case SchemeBasic:
+			challenges = append(challenges, fmt.Sprintf("Basic realm=%q", s.agentCard.Name))
+		}
+	}
+	return challenges
+}
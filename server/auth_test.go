@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"a2a/models"
+)
+
+type callerKey struct{}
+
+func TestA2AServer_Authenticator(t *testing.T) {
+	authenticate := func(r *http.Request) (context.Context, error) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			return nil, errors.New("bad token")
+		}
+		return context.WithValue(r.Context(), callerKey{}, "agent-a"), nil
+	}
+	var caller any
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		caller = ctx.Value(callerKey{})
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler, WithAuthenticator(authenticate))
+
+	// Refused calls get 401 without the authenticator's reason
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, newRPCRequest("message/send", sendParams("test-task-1", "Hello")))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	var response rpcResult
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error == nil || response.Error.Message != "Unauthorized" {
+		t.Errorf("Expected an unauthorized error, got %+v", response.Error)
+	}
+
+	// The card stays public
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, AgentCardPath, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the card without credentials, got status %d", w.Code)
+	}
+
+	req := newRPCRequest("message/send", sendParams("test-task-1", "Hello"))
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || caller != "agent-a" {
+		t.Errorf("Expected the handler to see the caller, got status %d caller %v", w.Code, caller)
+	}
+}
+
+func TestA2AServer_CardAuth(t *testing.T) {
+	card := mockAgentCard
+	card.Authentication = &models.AgentAuthentication{Schemes: []string{"Bearer", "apiKey", "basic"}}
+	var principal *Principal
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		principal, _ = PrincipalFromContext(ctx)
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	server := NewA2AServer(card, handler, WithCardAuth(AuthValidators{
+		Bearer:       StaticTokens(map[string]string{"bearer-token": "bearer-caller"}),
+		APIKey:       StaticTokens(map[string]string{"api-key": "key-caller"}),
+		APIKeyHeader: "X-Agent-Key",
+		Basic:        StaticCredentials(map[string]string{"alice": "wonderland"}),
+	}))
+
+	tests := []struct {
+		name    string
+		setAuth func(r *http.Request)
+		subject string
+		scheme  string
+	}{
+		{"no credentials", func(r *http.Request) {}, "", ""},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer bearer-token") }, "bearer-caller", SchemeBearer},
+		{"wrong bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, "", ""},
+		{"api key", func(r *http.Request) { r.Header.Set("X-Agent-Key", "api-key") }, "key-caller", SchemeAPIKey},
+		{"api key in default header", func(r *http.Request) { r.Header.Set("X-API-Key", "api-key") }, "", ""},
+		{"basic", func(r *http.Request) { r.SetBasicAuth("alice", "wonderland") }, "alice", SchemeBasic},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("alice", "looking-glass") }, "", ""},
+		{"unknown user", func(r *http.Request) { r.SetBasicAuth("bob", "") }, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal = nil
+			req := newRPCRequest("message/send", sendParams("task-"+tt.name, "Hello"))
+			tt.setAuth(req)
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+
+			if tt.subject == "" {
+				if w.Code != http.StatusUnauthorized {
+					t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+				}
+				challenges := w.Header().Values("WWW-Authenticate")
+				if len(challenges) != 2 || challenges[0] != `Bearer realm="Test Agent"` {
+					t.Errorf("Expected bearer and basic challenges, got %q", challenges)
+				}
+				return
+			}
+			if w.Code != http.StatusOK || principal == nil {
+				t.Fatalf("Expected the call to reach the handler, got status %d", w.Code)
+			}
+			if principal.Subject != tt.subject || principal.Scheme != tt.scheme {
+				t.Errorf("Expected %s via %s, got %+v", tt.subject, tt.scheme, principal)
+			}
+		})
+	}
+}
+
+func TestA2AServer_CardAuthOpenCard(t *testing.T) {
+	// A card without schemes leaves the agent open, even with validators configured
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithCardAuth(AuthValidators{}))
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, newRPCRequest("message/send", sendParams("test-task-1", "Hello")))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// A declared scheme without a validator refuses its callers
+	card := mockAgentCard
+	card.Authentication = &models.AgentAuthentication{Schemes: []string{SchemeBearer}}
+	server = NewA2AServer(card, mockTaskHandler, WithCardAuth(AuthValidators{}))
+	req := newRPCRequest("message/send", sendParams("test-task-1", "Hello"))
+	req.Header.Set("Authorization", "Bearer anything")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"a2a/models"
+)
+
+const (
+	// defaultMaxBatchSize is how many requests a single JSON-RPC batch may hold
+	defaultMaxBatchSize = 100
+	// defaultBatchParallelism is how many requests of a batch are run at the same time
+	defaultBatchParallelism = 8
+)
+
+// isStreamingMethod reports whether a method answers with an event stream
+func isStreamingMethod(method string) bool {
+	return method == "message/stream" || method == "tasks/resubscribe"
+}
+
+// serveBatch runs the requests of a JSON-RPC batch, a few at a time, and answers with an array
+// holding one response per request that is not a notification. The entries run concurrently,
+// so the order in which they take effect is not defined.
+func (s *A2AServer) serveBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(body, &entries); err != nil {
+		s.sendError(w, nullID, models.ErrorCodeParseError, "Invalid JSON")
+		return
+	}
+	if len(entries) == 0 {
+		s.sendError(w, nullID, models.ErrorCodeInvalidRequest, "Empty batch")
+		return
+	}
+	if len(entries) > s.maxBatchSize {
+		s.sendError(w, nullID, models.ErrorCodeInvalidRequest, fmt.Sprintf("Batch holds %d requests, the limit is %d", len(entries), s.maxBatchSize))
+		return
+	}
+
+	responses := make([]json.RawMessage, len(entries))
+	slots := make(chan struct{}, s.batchParallelism)
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			buffer := &responseBuffer{header: make(http.Header)}
+			s.serveRequest(buffer, r, entry, true)
+			responses[i] = bytes.TrimSpace(buffer.body.Bytes())
+		}()
+	}
+	wg.Wait()
+
+	var results []json.RawMessage
+	for _, response := range responses {
+		if len(response) > 0 {
+			results = append(results, response)
+		}
+	}
+
+	// A batch made up only of notifications gets no response
+	if len(results) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		s.logger.Printf("Error encoding batch response: %v", err)
+	}
+}
+
+// responseBuffer collects the response to one request of a batch
+type responseBuffer struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header         { return b.header }
+func (b *responseBuffer) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *responseBuffer) WriteHeader(int)             {}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+// batchResponse is one entry of a batch response, with the id kept as raw JSON
+type batchResponse struct {
+	ID     json.RawMessage      `json:"id"`
+	Result json.RawMessage      `json:"result"`
+	Error  *models.JSONRPCError `json:"error"`
+}
+
+func TestA2AServer_Batch(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	for _, id := range []string{"task-a", "task-b"} {
+		doRPC(t, server, "message/send", models.TaskSendParams{
+			ID:      id,
+			Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("hello")}}},
+		})
+	}
+
+	w := postRaw(server, `[
+		{"jsonrpc":"2.0","id":1,"method":"tasks/get","params":{"id":"task-a"}},
+		{"jsonrpc":"2.0","id":"two","method":"tasks/get","params":{"id":"task-b"}},
+		{"jsonrpc":"2.0","method":"tasks/get","params":{"id":"task-a"}},
+		{"jsonrpc":"2.0","id":3,"method":"tasks/get","params":{"id":"missing"}},
+		{"jsonrpc":"2.0","id":4,"method":"message/stream","params":{"id":"task-c","message":{"role":"user","parts":[{"text":"hi"}]}}},
+		5
+	]`)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var responses []batchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil {
+		t.Fatalf("Failed to decode batch response %q: %v", w.Body.String(), err)
+	}
+	if len(responses) != 5 {
+		t.Fatalf("Expected 5 responses (the notification gets none), got %d: %s", len(responses), w.Body.String())
+	}
+
+	for i, id := range []string{"task-a", "task-b"} {
+		var task models.Task
+		if err := json.Unmarshal(responses[i].Result, &task); err != nil || task.ID != id {
+			t.Errorf("Response %d: expected task %s, got %s (%v)", i, id, responses[i].Result, responses[i].Error)
+		}
+	}
+	if string(responses[0].ID) != "1" || string(responses[1].ID) != `"two"` {
+		t.Errorf("Expected ids 1 and \"two\", got %s and %s", responses[0].ID, responses[1].ID)
+	}
+
+	errors := []struct {
+		id   string
+		code models.ErrorCode
+	}{
+		{"3", models.ErrorCodeTaskNotFound},
+		{"4", models.ErrorCodeInvalidRequest},
+		{"null", models.ErrorCodeInvalidRequest},
+	}
+	for i, want := range errors {
+		response := responses[i+2]
+		if string(response.ID) != want.id {
+			t.Errorf("Response %d: expected id %s, got %s", i+2, want.id, response.ID)
+		}
+		if response.Error == nil || response.Error.Code != int(want.code) {
+			t.Errorf("Response %d: expected error %d, got %v", i+2, want.code, response.Error)
+		}
+	}
+
+	// The streaming request was refused before it started a task
+	if _, err := server.store.Get("task-c"); err == nil {
+		t.Error("Expected the streaming request not to run")
+	}
+}
+
+func TestA2AServer_BatchNotificationsOnly(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	w := postRaw(server, `[{"jsonrpc":"2.0","method":"tasks/get","params":{"id":"missing"}}]`)
+
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("Expected an empty %d response, got %d %q", http.StatusNoContent, w.Code, w.Body.String())
+	}
+}
+
+func TestA2AServer_BatchInvalid(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithBatchLimits(2, 1))
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", `[]`},
+		{"too large", `[{"jsonrpc":"2.0","id":1,"method":"tasks/get"},{"jsonrpc":"2.0","id":2,"method":"tasks/get"},{"jsonrpc":"2.0","id":3,"method":"tasks/get"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response batchResponse
+			if err := json.Unmarshal(postRaw(server, tt.body).Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidRequest) {
+				t.Errorf("Expected invalid request error, got %v", response.Error)
+			}
+		})
+	}
+}
+
+func TestA2AServer_BatchParallelism(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler, WithBatchLimits(10, 2))
+
+	var entries []string
+	for i := 0; i < 6; i++ {
+		entries = append(entries, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"message/send","params":{"id":"task-%d","message":{"role":"user","parts":[{"text":"hi"}]}}}`, i, i))
+	}
+	w := postRaw(server, "["+strings.Join(entries, ",")+"]")
+
+	var responses []batchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil {
+		t.Fatalf("Failed to decode batch response: %v", err)
+	}
+	if len(responses) != 6 {
+		t.Fatalf("Expected 6 responses, got %d", len(responses))
+	}
+	for i, response := range responses {
+		if response.Error != nil || string(response.ID) != fmt.Sprint(i) {
+			t.Errorf("Response %d: expected success for id %d, got id %s error %v", i, i, response.ID, response.Error)
+		}
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 handlers at once, saw %d", peak)
+	}
+	if peak < 2 {
+		t.Errorf("Expected batch entries to run in parallel, saw %d at most", peak)
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"a2a/models"
+)
+
+// AgentCardPath is where an agent's card is published, relative to the agent's endpoint
+const AgentCardPath = "/.well-known/agent.json"
+
+// AgentCard returns the card this server publishes
+func (s *A2AServer) AgentCard() models.AgentCard {
+	return s.agentCard
+}
+
+// Mount registers the server on mux at path, together with its card at path + AgentCardPath and
+// its push notification keys at path + JWKSPath
+func (s *A2AServer) Mount(mux *http.ServeMux, path string) {
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		path = "/"
+	}
+	mux.Handle(path, s)
+	mux.Handle(strings.TrimSuffix(path, "/")+AgentCardPath, s)
+	mux.Handle(strings.TrimSuffix(path, "/")+JWKSPath, s)
+}
+
+// isAgentCardPath reports whether r asks for the well-known card rather than the RPC endpoint
+func isAgentCardPath(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, AgentCardPath)
+}
+
+// serveAgentCard answers a request for the agent card. The well-known path only serves the card,
+// so methods other than GET are refused there.
+func (s *A2AServer) serveAgentCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.writeJSON(w, http.StatusMethodNotAllowed, errorResponse(nullID, NewError(models.ErrorCodeInvalidRequest, "Method not allowed")))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.agentCard); err != nil {
+		s.logger.Printf("Error encoding agent card: %v", err)
+	}
+}
+
+// AgentRegistry is the document served by a registry handler
+type AgentRegistry struct {
+	Agents []models.AgentCard `json:"agents"`
+}
+
+// NewRegistryHandler returns a handler that lists the cards of servers, in order, so that
+// clients can discover every agent a process hosts with a single GET
+func NewRegistryHandler(servers ...*A2AServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		registry := AgentRegistry{Agents: make([]models.AgentCard, 0, len(servers))}
+		for _, s := range servers {
+			registry.Agents = append(registry.Agents, s.AgentCard())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(registry); err != nil {
+			log.Printf("Error encoding agent registry: %v", err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"a2a/models"
+)
+
+func TestA2AServer_WellKnownAgentCard(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	mux := http.NewServeMux()
+	server.Mount(mux, "/agent/finance/")
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	for _, path := range []string{"/agent/finance", "/agent/finance" + AgentCardPath} {
+		resp, err := http.Get(httpServer.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		var card models.AgentCard
+		err = json.NewDecoder(resp.Body).Decode(&card)
+		_ = resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: expected the agent card, got status %d (%v)", path, resp.StatusCode, err)
+		}
+		if card.Name != mockAgentCard.Name {
+			t.Errorf("GET %s: expected card %q, got %q", path, mockAgentCard.Name, card.Name)
+		}
+	}
+
+	// The well-known path only serves the card
+	resp, err := http.Post(httpServer.URL+"/agent/finance"+AgentCardPath, "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tasks/get","params":{"id":"x"}}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET, HEAD" {
+		t.Errorf("Expected 405 with Allow GET, HEAD, got %d %q", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+}
+
+func TestRegistryHandler(t *testing.T) {
+	other := mockAgentCard
+	other.Name = "Other Agent"
+	handler := NewRegistryHandler(NewA2AServer(mockAgentCard, mockTaskHandler), NewA2AServer(other, mockTaskHandler))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/agents", nil))
+	var registry AgentRegistry
+	if err := json.Unmarshal(w.Body.Bytes(), &registry); err != nil {
+		t.Fatalf("Failed to decode registry: %v", err)
+	}
+	if len(registry.Agents) != 2 || registry.Agents[0].Name != mockAgentCard.Name || registry.Agents[1].Name != "Other Agent" {
+		t.Errorf("Expected both cards in order, got %+v", registry.Agents)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/agents", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
@@ -0,0 +1,379 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"a2a/internal/jose"
+)
+
+// Duration is a time.Duration written in JSON as a string such as "30s" or "1m30s"
+type Duration time.Duration
+
+// MarshalJSON writes the duration in time.Duration's string form
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON reads a duration string accepted by time.ParseDuration
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Config describes a Host and the agents it serves, so a deployment can be changed without
+// recompiling. It is read from a JSON file with LoadFile and from A2A_* environment variables
+// with LoadEnv, in that order, so the environment wins.
+type Config struct {
+	// Listen is the address the host listens on, such as ":8080"
+	Listen string `json:"listen"`
+	// PublicURL is the base URL agents are reached at when it differs from the listen address
+	PublicURL string `json:"publicUrl,omitempty"`
+	// ShutdownTimeout bounds how long shutdown waits for in-flight work
+	ShutdownTimeout Duration `json:"shutdownTimeout,omitempty"`
+	// ReadHeaderTimeout bounds how long a client may take to send request headers
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout,omitempty"`
+	// DataDir holds the task stores of agents using the file store, one directory per agent
+	DataDir string `json:"dataDir,omitempty"`
+	// AdminToken, when set, serves the push delivery report to requests bearing it
+	AdminToken string `json:"adminToken,omitempty"`
+	// Agents lists the agents to serve
+	Agents []AgentConfig `json:"agents"`
+}
+
+// AgentConfig describes one agent served by a Host
+type AgentConfig struct {
+	// Name identifies the agent; the program maps it to the agent's implementation
+	Name string `json:"name"`
+	// Path is the prefix the agent is served under
+	Path string `json:"path"`
+	// Store is "file" (the default) to keep tasks under DataDir, or "memory"
+	Store string `json:"store,omitempty"`
+	// HandlerTimeout bounds each handler run
+	HandlerTimeout Duration `json:"handlerTimeout,omitempty"`
+	// PushTimeout bounds each push notification request
+	PushTimeout Duration `json:"pushTimeout,omitempty"`
+	// HeartbeatInterval is how often idle streams get a keepalive
+	HeartbeatInterval Duration `json:"heartbeatInterval,omitempty"`
+	// MaxBodySize caps request bodies in bytes
+	MaxBodySize int64 `json:"maxBodySize,omitempty"`
+	// AsyncSend makes message/send return before the handler runs unless the caller blocks
+	AsyncSend bool `json:"asyncSend,omitempty"`
+	// Workers and QueueSize size the background pool for non-blocking sends
+	Workers   int `json:"workers,omitempty"`
+	QueueSize int `json:"queueSize,omitempty"`
+	// CORS lets browsers on other origins call the agent
+	CORS *CORSPolicy `json:"cors,omitempty"`
+	// PushSigningKey is a PEM file holding the key push notifications are signed with
+	PushSigningKey string `json:"pushSigningKey,omitempty"`
+	// PushMaxAttempts, PushInitialBackoff and PushMaxBackoff control push notification retries
+	PushMaxAttempts    int      `json:"pushMaxAttempts,omitempty"`
+	PushInitialBackoff Duration `json:"pushInitialBackoff,omitempty"`
+	PushMaxBackoff     Duration `json:"pushMaxBackoff,omitempty"`
+	// PushAllowPrivate lets push notifications reach loopback and private addresses
+	PushAllowPrivate bool `json:"pushAllowPrivate,omitempty"`
+	// Auth, when set, replaces the agent's own credential checks for the schemes on its card
+	Auth *AuthConfig `json:"auth,omitempty"`
+}
+
+// AuthConfig sets the credentials an agent accepts for the authentication schemes its card
+// declares. Schemes left unset here refuse every caller.
+type AuthConfig struct {
+	// BearerTokens maps accepted bearer tokens to the caller each one identifies
+	BearerTokens map[string]string `json:"bearerTokens,omitempty"`
+	// JWT checks bearer tokens as signed JWTs instead of against BearerTokens
+	JWT *JWTAuthConfig `json:"jwt,omitempty"`
+	// APIKeys maps accepted API keys to the caller each one identifies
+	APIKeys map[string]string `json:"apiKeys,omitempty"`
+	// APIKeyHeader is the header API keys are read from (default X-API-Key)
+	APIKeyHeader string `json:"apiKeyHeader,omitempty"`
+	// BasicUsers maps the usernames accepted with HTTP basic auth to their passwords
+	BasicUsers map[string]string `json:"basicUsers,omitempty"`
+}
+
+// JWTAuthConfig describes how bearer JWTs are checked
+type JWTAuthConfig struct {
+	// JWKS is the file path or http(s) URL of the keys tokens are signed with
+	JWKS string `json:"jwks"`
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+	// Claims maps token claims to the principal claims they are copied to
+	Claims map[string]string `json:"claims,omitempty"`
+}
+
+// validators builds the validators for the schemes the config sets
+func (a *AuthConfig) validators() (AuthValidators, error) {
+	validators := AuthValidators{APIKeyHeader: a.APIKeyHeader}
+	if a.JWT != nil {
+		keys, err := LoadJWKS(a.JWT.JWKS)
+		if err != nil {
+			return AuthValidators{}, fmt.Errorf("load JWKS: %w", err)
+		}
+		validators.Bearer = JWTValidator(JWTConfig{
+			Keys:     keys,
+			Issuer:   a.JWT.Issuer,
+			Audience: a.JWT.Audience,
+			Claims:   a.JWT.Claims,
+		})
+	} else if len(a.BearerTokens) > 0 {
+		validators.Bearer = StaticTokens(a.BearerTokens)
+	}
+	if len(a.APIKeys) > 0 {
+		validators.APIKey = StaticTokens(a.APIKeys)
+	}
+	if len(a.BasicUsers) > 0 {
+		validators.Basic = StaticCredentials(a.BasicUsers)
+	}
+	return validators, nil
+}
+
+// LoadFile reads a JSON config file over c. Fields the file leaves out keep their current
+// values; a file that lists agents replaces the whole list. Unknown fields are an error, so
+// typos do not go unnoticed.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadEnv applies the A2A_* variables found by lookup, usually os.LookupEnv:
+//
+//	A2A_LISTEN, A2A_PUBLIC_URL, A2A_DATA_DIR, A2A_SHUTDOWN_TIMEOUT, A2A_READ_HEADER_TIMEOUT,
+//	A2A_ADMIN_TOKEN
+//
+// set the host settings, and these set the matching field of every agent:
+//
+//	A2A_HANDLER_TIMEOUT, A2A_MAX_BODY_SIZE, A2A_CORS_ORIGINS (comma separated)
+//	A2A_BEARER_TOKENS, A2A_API_KEYS (comma separated token=caller pairs)
+func (c *Config) LoadEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	setString := func(name string, field *string) {
+		if value, ok := lookup(name); ok {
+			*field = value
+		}
+	}
+	setDuration := func(name string, field *Duration) {
+		if value, ok := lookup(name); ok {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*field = Duration(parsed)
+		}
+	}
+
+	setString("A2A_LISTEN", &c.Listen)
+	setString("A2A_PUBLIC_URL", &c.PublicURL)
+	setString("A2A_DATA_DIR", &c.DataDir)
+	setDuration("A2A_SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+	setDuration("A2A_READ_HEADER_TIMEOUT", &c.ReadHeaderTimeout)
+	setString("A2A_ADMIN_TOKEN", &c.AdminToken)
+
+	for i := range c.Agents {
+		agent := &c.Agents[i]
+		setDuration("A2A_HANDLER_TIMEOUT", &agent.HandlerTimeout)
+		if value, ok := lookup("A2A_MAX_BODY_SIZE"); ok {
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("A2A_MAX_BODY_SIZE: %w", err))
+			} else {
+				agent.MaxBodySize = size
+			}
+		}
+		if value, ok := lookup("A2A_CORS_ORIGINS"); ok {
+			agent.CORS = &CORSPolicy{AllowedOrigins: splitList(value)}
+		}
+		for _, name := range []string{"A2A_BEARER_TOKENS", "A2A_API_KEYS"} {
+			value, ok := lookup(name)
+			if !ok {
+				continue
+			}
+			tokens, err := parseTokens(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			if agent.Auth == nil {
+				agent.Auth = &AuthConfig{}
+			}
+			if name == "A2A_BEARER_TOKENS" {
+				agent.Auth.BearerTokens = tokens
+				agent.Auth.JWT = nil
+			} else {
+				agent.Auth.APIKeys = tokens
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// splitList splits a comma separated list, trimming spaces and dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseTokens reads a comma separated list of token=caller pairs
+func parseTokens(value string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, pair := range splitList(value) {
+		token, caller, ok := strings.Cut(pair, "=")
+		if !ok || token == "" || caller == "" {
+			return nil, fmt.Errorf("%q is not a token=caller pair", pair)
+		}
+		tokens[token] = caller
+	}
+	return tokens, nil
+}
+
+// Validate checks that the config describes at least one agent and that agents have distinct
+// names and paths
+func (c *Config) Validate() error {
+	if len(c.Agents) == 0 {
+		return errors.New("config lists no agents")
+	}
+	names := make(map[string]bool, len(c.Agents))
+	paths := make(map[string]bool, len(c.Agents))
+	for i, agent := range c.Agents {
+		if agent.Name == "" {
+			return fmt.Errorf("agent %d has no name", i)
+		}
+		if agent.Path == "" {
+			return fmt.Errorf("agent %q has no path", agent.Name)
+		}
+		path := "/" + strings.Trim(agent.Path, "/")
+		if names[agent.Name] {
+			return fmt.Errorf("agent %q is listed twice", agent.Name)
+		}
+		if paths[path] {
+			return fmt.Errorf("agent %q: path %s is already in use", agent.Name, path)
+		}
+		if agent.Store != "" && agent.Store != "file" && agent.Store != "memory" {
+			return fmt.Errorf("agent %q: unknown store %q", agent.Name, agent.Store)
+		}
+		if auth := agent.Auth; auth != nil && auth.JWT != nil {
+			if auth.JWT.JWKS == "" {
+				return fmt.Errorf("agent %q: auth.jwt needs a jwks", agent.Name)
+			}
+			if len(auth.BearerTokens) > 0 {
+				return fmt.Errorf("agent %q: auth sets both bearerTokens and jwt", agent.Name)
+			}
+		}
+		names[agent.Name] = true
+		paths[path] = true
+	}
+	return nil
+}
+
+// HostOptions returns the options for a Host serving c
+func (c *Config) HostOptions() []HostOption {
+	var opts []HostOption
+	if c.PublicURL != "" {
+		opts = append(opts, WithPublicURL(c.PublicURL))
+	}
+	if c.ShutdownTimeout > 0 {
+		opts = append(opts, WithShutdownTimeout(time.Duration(c.ShutdownTimeout)))
+	}
+	if c.ReadHeaderTimeout > 0 {
+		opts = append(opts, WithReadHeaderTimeout(time.Duration(c.ReadHeaderTimeout)))
+	}
+	if c.AdminToken != "" {
+		opts = append(opts, WithAdmin(BearerAuthenticator(StaticTokens(map[string]string{c.AdminToken: "admin"}))))
+	}
+	return opts
+}
+
+// AgentOptions returns the options for the agent, opening its task store and dead letters
+// under the config's DataDir. The stores are opened last, once nothing else can fail, and are
+// closed by the agent's Close, which a Host calls when it shuts down. The options are meant to follow the agent's own, so that settings such
+// as Auth override what the agent sets up by default.
+func (c *Config) AgentOptions(agent AgentConfig) ([]Option, error) {
+	var opts []Option
+	if agent.Auth != nil {
+		validators, err := agent.Auth.validators()
+		if err != nil {
+			return nil, fmt.Errorf("configure auth for %s: %w", agent.Name, err)
+		}
+		opts = append(opts, WithCardAuth(validators))
+	}
+	if agent.HandlerTimeout > 0 {
+		opts = append(opts, WithHandlerTimeout(time.Duration(agent.HandlerTimeout)))
+	}
+	if agent.PushTimeout > 0 {
+		opts = append(opts, WithPushTimeout(time.Duration(agent.PushTimeout)))
+	}
+	opts = append(opts, WithPushDelivery(DeliveryPolicy{
+		MaxAttempts:           agent.PushMaxAttempts,
+		InitialBackoff:        time.Duration(agent.PushInitialBackoff),
+		MaxBackoff:            time.Duration(agent.PushMaxBackoff),
+		AllowPrivateAddresses: agent.PushAllowPrivate,
+	}))
+	if agent.HeartbeatInterval > 0 {
+		opts = append(opts, WithHeartbeatInterval(time.Duration(agent.HeartbeatInterval)))
+	}
+	if agent.MaxBodySize > 0 {
+		opts = append(opts, WithMaxBodySize(agent.MaxBodySize))
+	}
+	if agent.AsyncSend {
+		opts = append(opts, WithAsyncSend(true))
+	}
+	if agent.Workers > 0 || agent.QueueSize > 0 {
+		queueSize := agent.QueueSize
+		if queueSize == 0 {
+			queueSize = defaultQueueSize
+		}
+		opts = append(opts, WithWorkerPool(agent.Workers, queueSize))
+	}
+	if agent.CORS != nil {
+		opts = append(opts, WithCORS(*agent.CORS))
+	}
+	if agent.PushSigningKey != "" {
+		key, err := jose.LoadPrivateKeyFile(agent.PushSigningKey)
+		if err != nil {
+			return nil, fmt.Errorf("load push signing key for %s: %w", agent.Name, err)
+		}
+		opts = append(opts, WithPushSigningKey(key))
+	}
+	if agent.Store != "memory" {
+		store, err := NewFileTaskStore(filepath.Join(c.DataDir, agent.Name))
+		if err != nil {
+			return nil, fmt.Errorf("open task store for %s: %w", agent.Name, err)
+		}
+		deadLetters, err := NewFileDeadLetterStore(filepath.Join(c.DataDir, agent.Name, deadLetterFileName))
+		if err != nil {
+			_ = store.Close()
+			return nil, fmt.Errorf("open dead letters for %s: %w", agent.Name, err)
+		}
+		opts = append(opts, WithTaskStore(store), WithDeadLetterStore(deadLetters), WithClosers(store, deadLetters))
+	}
+	return opts, nil
+}
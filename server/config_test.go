@@ -0,0 +1,233 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+func TestConfig_LoadFileAndEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{
+		"listen": ":9090",
+		"shutdownTimeout": "5s",
+		"agents": [
+			{"name": "finance", "path": "/agent/finance", "handlerTimeout": "1m"},
+			{"name": "compliance", "path": "/agent/compliance", "store": "memory"}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg := Config{Listen: ":8080", DataDir: "data"}
+	if err := cfg.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.Listen != ":9090" || cfg.DataDir != "data" || cfg.ShutdownTimeout != Duration(5*time.Second) {
+		t.Errorf("Expected file values over the defaults, got %+v", cfg)
+	}
+	if len(cfg.Agents) != 2 || cfg.Agents[0].HandlerTimeout != Duration(time.Minute) {
+		t.Fatalf("Expected both agents from the file, got %+v", cfg.Agents)
+	}
+
+	env := map[string]string{
+		"A2A_LISTEN":          ":7070",
+		"A2A_HANDLER_TIMEOUT": "30s",
+		"A2A_CORS_ORIGINS":    "http://a.example, http://b.example,",
+		"A2A_ADMIN_TOKEN":     "admin-token",
+		"A2A_BEARER_TOKENS":   "token-a=agent-a, token-b=agent-b",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	if err := cfg.LoadEnv(lookup); err != nil {
+		t.Fatalf("LoadEnv failed: %v", err)
+	}
+	if cfg.Listen != ":7070" || cfg.AdminToken != "admin-token" {
+		t.Errorf("Expected the environment to win, got listen %s", cfg.Listen)
+	}
+	for _, agent := range cfg.Agents {
+		if agent.HandlerTimeout != Duration(30*time.Second) || agent.CORS == nil {
+			t.Errorf("Expected agent settings from the environment, got %+v", agent)
+			continue
+		}
+		if got := strings.Join(agent.CORS.AllowedOrigins, ","); got != "http://a.example,http://b.example" {
+			t.Errorf("Expected trimmed origins, got %q", got)
+		}
+		if agent.Auth == nil || agent.Auth.BearerTokens["token-b"] != "agent-b" || len(agent.Auth.BearerTokens) != 2 {
+			t.Errorf("Expected bearer tokens from the environment, got %+v", agent.Auth)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+}
+
+func TestConfig_Errors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"listen": ":8080", "agnets": []}`), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	var cfg Config
+	if err := cfg.LoadFile(path); err == nil || !strings.Contains(err.Error(), "agnets") {
+		t.Errorf("Expected an unknown field error, got %v", err)
+	}
+
+	lookup := func(name string) (string, bool) { return "soon", name == "A2A_SHUTDOWN_TIMEOUT" }
+	if err := cfg.LoadEnv(lookup); err == nil || !strings.Contains(err.Error(), "A2A_SHUTDOWN_TIMEOUT") {
+		t.Errorf("Expected an invalid duration error, got %v", err)
+	}
+	withAgent := Config{Agents: []AgentConfig{{Name: "a", Path: "/a"}}}
+	lookup = func(name string) (string, bool) { return "no-caller", name == "A2A_BEARER_TOKENS" }
+	if err := withAgent.LoadEnv(lookup); err == nil || !strings.Contains(err.Error(), "A2A_BEARER_TOKENS") {
+		t.Errorf("Expected an invalid token list error, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		agents []AgentConfig
+	}{
+		{"no agents", nil},
+		{"no name", []AgentConfig{{Path: "/a"}}},
+		{"no path", []AgentConfig{{Name: "a"}}},
+		{"duplicate name", []AgentConfig{{Name: "a", Path: "/a"}, {Name: "a", Path: "/b"}}},
+		{"duplicate path", []AgentConfig{{Name: "a", Path: "/a"}, {Name: "b", Path: "/a/"}}},
+		{"unknown store", []AgentConfig{{Name: "a", Path: "/a", Store: "redis"}}},
+		{"jwt without jwks", []AgentConfig{{Name: "a", Path: "/a", Auth: &AuthConfig{JWT: &JWTAuthConfig{}}}}},
+		{"jwt and tokens", []AgentConfig{{Name: "a", Path: "/a", Auth: &AuthConfig{
+			BearerTokens: map[string]string{"t": "a"},
+			JWT:          &JWTAuthConfig{JWKS: "keys.json"},
+		}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Agents: tt.agents}
+			if err := cfg.Validate(); err == nil {
+				t.Error("Expected a validation error")
+			}
+		})
+	}
+}
+
+func TestConfig_AgentOptions(t *testing.T) {
+	cfg := Config{DataDir: t.TempDir()}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "push.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	opts, err := cfg.AgentOptions(AgentConfig{
+		Name:             "finance",
+		Path:             "/agent/finance",
+		HandlerTimeout:   Duration(time.Minute),
+		MaxBodySize:      1024,
+		Workers:          2,
+		PushSigningKey:   keyPath,
+		PushMaxAttempts:  7,
+		PushAllowPrivate: true,
+	})
+	if err != nil {
+		t.Fatalf("AgentOptions failed: %v", err)
+	}
+
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, opts...)
+	if _, ok := server.store.(*FileTaskStore); !ok {
+		t.Errorf("Expected a file task store, got %T", server.store)
+	}
+	if server.handlerTimeout != time.Minute || server.maxBodySize != 1024 {
+		t.Errorf("Expected configured limits, got timeout %v body %d", server.handlerTimeout, server.maxBodySize)
+	}
+	if server.workers != 2 || server.queueSize != defaultQueueSize {
+		t.Errorf("Expected 2 workers and the default queue, got %d and %d", server.workers, server.queueSize)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.DataDir, "finance")); err != nil {
+		t.Errorf("Expected the store under the data directory: %v", err)
+	}
+	if _, ok := server.deadLetters.(*FileDeadLetterStore); !ok {
+		t.Errorf("Expected a file dead letter store, got %T", server.deadLetters)
+	}
+	if server.deliveryPolicy.MaxAttempts != 7 || !server.deliveryPolicy.AllowPrivateAddresses || server.deliveryPolicy.MaxBackoff != defaultPushMaxBackoff {
+		t.Errorf("Expected the configured delivery policy over the defaults, got %+v", server.deliveryPolicy)
+	}
+	if server.pushSigner == nil || !server.pushSigner.key.(*ecdsa.PrivateKey).Equal(key) {
+		t.Error("Expected push notifications to be signed with the configured key")
+	}
+
+	// A bad signing key is caught before any store is opened, so nothing is left open
+	if _, err := cfg.AgentOptions(AgentConfig{Name: "broken", PushSigningKey: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("Expected an error for a missing signing key")
+	}
+	if _, err := os.Stat(filepath.Join(cfg.DataDir, "broken")); !os.IsNotExist(err) {
+		t.Errorf("Expected no store for an agent that failed to configure, got %v", err)
+	}
+
+	// The stores opened for the agent are released by Close
+	if err := server.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := server.store.Put(&models.Task{ID: "task-1"}); err == nil {
+		t.Error("Expected the task store to be closed")
+	}
+}
+
+func TestConfig_AgentAuth(t *testing.T) {
+	card := mockAgentCard
+	card.Authentication = &models.AgentAuthentication{Schemes: []string{SchemeBearer, SchemeBasic}}
+	builtIn := WithCardAuth(AuthValidators{Bearer: StaticTokens(map[string]string{"built-in": "agent-a"})})
+
+	cfg := Config{}
+	opts, err := cfg.AgentOptions(AgentConfig{Name: "a", Store: "memory", Auth: &AuthConfig{
+		BearerTokens: map[string]string{"configured": "agent-a"},
+		BasicUsers:   map[string]string{"auditor": "secret"},
+	}})
+	if err != nil {
+		t.Fatalf("AgentOptions failed: %v", err)
+	}
+	// Configured auth follows the agent's own, as cmd/server passes it
+	server := NewA2AServer(card, mockTaskHandler, append([]Option{builtIn}, opts...)...)
+
+	for token, want := range map[string]bool{"configured": true, "built-in": false} {
+		req := newRPCRequest("tasks/get", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}})
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if accepted := w.Code == http.StatusOK; accepted != want {
+			t.Errorf("Token %q: expected accepted=%v, got status %d", token, want, w.Code)
+		}
+	}
+	for password, want := range map[string]bool{"secret": true, "wrong": false} {
+		req := newRPCRequest("tasks/get", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "missing"}})
+		req.SetBasicAuth("auditor", password)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if accepted := w.Code == http.StatusOK; accepted != want {
+			t.Errorf("Password %q: expected accepted=%v, got status %d", password, want, w.Code)
+		}
+	}
+
+	if _, err := cfg.AgentOptions(AgentConfig{Name: "a", Store: "memory", Auth: &AuthConfig{
+		JWT: &JWTAuthConfig{JWKS: filepath.Join(t.TempDir(), "missing.json")},
+	}}); err == nil {
+		t.Error("Expected an error for a missing JWKS")
+	}
+}
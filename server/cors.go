@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy lets browser clients on other origins call an agent
+type CORSPolicy struct {
+	// AllowedOrigins lists the origins allowed to call the agent; "*" allows any origin
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// AllowedHeaders lists request headers beyond Content-Type that callers may send,
+	// such as Authorization
+	AllowedHeaders []string `json:"allowedHeaders,omitempty"`
+	// MaxAge is how long browsers may cache a preflight answer
+	MaxAge Duration `json:"maxAge,omitempty"`
+}
+
+// allows reports whether origin may call the agent
+func (p *CORSPolicy) allows(origin string) bool {
+	return slices.Contains(p.AllowedOrigins, "*") || slices.Contains(p.AllowedOrigins, origin)
+}
+
+// handle adds the CORS headers for an allowed origin and answers preflight requests, reporting
+// whether the request was a preflight and has been answered. Requests from other origins get
+// no CORS headers, so browsers keep them from reading the response.
+func (p *CORSPolicy) handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	w.Header().Add("Vary", "Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if !p.allows(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if !preflight {
+		return false
+	}
+
+	// Last-Event-ID lets browser clients resume streams
+	headers := append([]string{"Content-Type", "Last-Event-ID"}, p.AllowedHeaders...)
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	if p.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(time.Duration(p.MaxAge).Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestA2AServer_CORS(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithCORS(CORSPolicy{
+		AllowedOrigins: []string{"http://app.example"},
+		AllowedHeaders: []string{"Authorization"},
+		MaxAge:         Duration(time.Minute),
+	}))
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+
+	w := preflight("http://app.example")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "http://app.example" {
+		t.Errorf("Expected the origin to be allowed, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, Last-Event-ID, Authorization" {
+		t.Errorf("Unexpected allowed headers %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "60" {
+		t.Errorf("Expected max age 60, got %q", got)
+	}
+
+	w = preflight("http://evil.example")
+	if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected other origins to be refused, got %d %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// Actual calls from an allowed origin carry the header too
+	req := newRPCRequest("tasks/get", map[string]string{"id": "missing"})
+	req.Header.Set("Origin", "http://app.example")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "http://app.example" {
+		t.Errorf("Expected the origin on the response, got %q", got)
+	}
+}
+
+func TestA2AServer_CORSStream(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithCORS(CORSPolicy{AllowedOrigins: []string{"http://app.example"}}))
+
+	stream := func(taskID, origin string) *httptest.ResponseRecorder {
+		req := newRPCRequest("message/stream", sendParams(taskID, "Hello"))
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+
+	// Streams follow the policy like any other response
+	if got := stream("test-task-1", "http://evil.example").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS header on a stream to a disallowed origin, got %q", got)
+	}
+	if got := stream("test-task-2", "http://app.example").Header().Get("Access-Control-Allow-Origin"); got != "http://app.example" {
+		t.Errorf("Expected the origin on a stream to an allowed origin, got %q", got)
+	}
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// maxDeadLetters is how many dead letters a store keeps; the oldest are dropped beyond it
+	maxDeadLetters = 1000
+	// deadLetterFileName is the file an agent's dead letters are kept in under the data directory
+	deadLetterFileName = "deadletters.log"
+)
+
+// ErrDeadLetterNotFound is returned by a DeadLetterStore when no dead letter has the given ID
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter records a push notification that could not be delivered
+type DeadLetter struct {
+	ID     string `json:"id"`
+	TaskID string `json:"taskId"`
+	URL    string `json:"url"`
+	// Event is the notification body that was to be delivered
+	Event json.RawMessage `json:"event"`
+	// Attempts is how many times delivery was tried
+	Attempts int `json:"attempts"`
+	// Error describes why the last attempt failed
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// DeadLetterStore keeps the push notifications that ran out of delivery attempts.
+// Implementations must be safe for concurrent use.
+type DeadLetterStore interface {
+	// Add records a dead letter
+	Add(letter DeadLetter) error
+	// List returns the dead letters, oldest first
+	List() ([]DeadLetter, error)
+	// Delete removes a dead letter, or returns ErrDeadLetterNotFound
+	Delete(id string) error
+}
+
+// MemoryDeadLetterStore is a DeadLetterStore that keeps the most recent dead letters in
+// process memory
+type MemoryDeadLetterStore struct {
+	letters []DeadLetter
+	mu      sync.Mutex
+}
+
+// NewMemoryDeadLetterStore creates an empty in-memory dead letter store
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{}
+}
+
+// Add records a dead letter, dropping the oldest one when the store is full
+func (m *MemoryDeadLetterStore) Add(letter DeadLetter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.letters = append(m.letters, letter)
+	if len(m.letters) > maxDeadLetters {
+		m.letters = append([]DeadLetter(nil), m.letters[len(m.letters)-maxDeadLetters:]...)
+	}
+	return nil
+}
+
+// List returns a copy of the dead letters, oldest first
+func (m *MemoryDeadLetterStore) List() ([]DeadLetter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DeadLetter{}, m.letters...), nil
+}
+
+// Delete removes a dead letter
+func (m *MemoryDeadLetterStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, letter := range m.letters {
+		if letter.ID == id {
+			m.letters = append(m.letters[:i], m.letters[i+1:]...)
+			return nil
+		}
+	}
+	return ErrDeadLetterNotFound
+}
+
+// FileDeadLetterStore is a DeadLetterStore that survives restarts. Dead letters are appended to
+// a JSON lines file and synced before Add returns; the file is rewritten when letters are
+// deleted or the oldest are dropped.
+type FileDeadLetterStore struct {
+	path   string
+	file   *os.File
+	memory *MemoryDeadLetterStore
+	// lines counts the records in the file, which can exceed the letters kept in memory
+	lines int
+	mu    sync.Mutex
+}
+
+// NewFileDeadLetterStore opens (or creates) the dead letter file at path and loads its letters
+func NewFileDeadLetterStore(path string) (*FileDeadLetterStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create dead letter directory: %w", err)
+	}
+	f := &FileDeadLetterStore{path: path, memory: NewMemoryDeadLetterStore()}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open dead letters: %w", err)
+	}
+	f.file = file
+	return f, nil
+}
+
+// load reads the letters in the file. A torn last line, left by a crash in the middle of a
+// write, is cut off so that new letters start cleanly. A complete line that cannot be read is
+// corruption rather than a torn write, so loading fails and the file is left as it is.
+func (f *FileDeadLetterStore) load() error {
+	file, err := os.OpenFile(f.path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open dead letters: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := bufio.NewReader(file)
+	var valid int64
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Anything after the last newline is an incomplete letter
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read dead letters: %w", err)
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal(bytes.TrimSpace(line), &letter); err != nil {
+			return fmt.Errorf("dead letters %s line %d is corrupt: %w", f.path, lineNumber, err)
+		}
+		_ = f.memory.Add(letter)
+		valid += int64(len(line))
+		f.lines++
+	}
+
+	if err := file.Truncate(valid); err != nil {
+		return fmt.Errorf("truncate dead letters: %w", err)
+	}
+	return nil
+}
+
+// Add appends a dead letter to the file
+func (f *FileDeadLetterStore) Add(letter DeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("encode dead letter: %w", err)
+	}
+	if _, err := f.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write dead letter: %w", err)
+	}
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("sync dead letters: %w", err)
+	}
+	_ = f.memory.Add(letter)
+	f.lines++
+	if f.lines > 2*maxDeadLetters {
+		return f.rewrite()
+	}
+	return nil
+}
+
+// List returns the dead letters, oldest first
+func (f *FileDeadLetterStore) List() ([]DeadLetter, error) {
+	return f.memory.List()
+}
+
+// Delete removes a dead letter and rewrites the file without it
+func (f *FileDeadLetterStore) Delete(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.memory.Delete(id); err != nil {
+		return err
+	}
+	return f.rewrite()
+}
+
+// rewrite replaces the file with the letters kept in memory. The new file is written next to
+// the old one and renamed over it, so a crash leaves one or the other intact. Must be called
+// with f.mu held.
+func (f *FileDeadLetterStore) rewrite() error {
+	letters, err := f.memory.List()
+	if err != nil {
+		return err
+	}
+
+	tmpPath := f.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("create dead letter file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, letter := range letters {
+		if err := encoder.Encode(letter); err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("write dead letters: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write dead letters: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("sync dead letters: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close dead letters: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		return fmt.Errorf("replace dead letters: %w", err)
+	}
+	if err := syncDir(filepath.Dir(f.path)); err != nil {
+		return err
+	}
+
+	// Reopen so further appends go to the new file
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("reopen dead letters: %w", err)
+	}
+	_ = f.file.Close()
+	f.file = file
+	f.lines = len(letters)
+	return nil
+}
+
+// Close closes the dead letter file
+func (f *FileDeadLetterStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileDeadLetterStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent", deadLetterFileName)
+	store, err := NewFileDeadLetterStore(path)
+	if err != nil {
+		t.Fatalf("NewFileDeadLetterStore failed: %v", err)
+	}
+	for i := range 3 {
+		letter := DeadLetter{ID: fmt.Sprint(i), TaskID: "task-1", URL: "https://hooks.example.com", Event: json.RawMessage(`{"id":"task-1"}`), Attempts: 5, FailedAt: time.Now().UTC()}
+		if err := store.Add(letter); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := store.Delete("1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Delete("1"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("Expected ErrDeadLetterNotFound, got %v", err)
+	}
+	_ = store.Close()
+
+	// A crash in the middle of a write leaves a torn line behind
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	_, _ = file.WriteString(`{"id":"torn","ta`)
+	_ = file.Close()
+
+	reopened, err := NewFileDeadLetterStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer func() { _ = reopened.Close() }()
+	if err := reopened.Add(DeadLetter{ID: "3"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	letters, err := reopened.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	var ids []string
+	for _, letter := range letters {
+		ids = append(ids, letter.ID)
+	}
+	if fmt.Sprint(ids) != "[0 2 3]" || string(letters[0].Event) != `{"id":"task-1"}` {
+		t.Errorf("Expected letters 0, 2 and 3 to survive, got %v", ids)
+	}
+}
+
+func TestFileDeadLetterStore_CorruptLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), deadLetterFileName)
+	content := "{\"id\":\"0\"}\n[]\n{\"id\":\"2\"}\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if _, err := NewFileDeadLetterStore(path); err == nil {
+		t.Fatal("Expected a corrupt line to make opening the store fail")
+	}
+	if after, _ := os.ReadFile(path); string(after) != content {
+		t.Errorf("Expected the file to be left untouched, got %q", after)
+	}
+}
+
+func TestMemoryDeadLetterStore_KeepsNewest(t *testing.T) {
+	store := NewMemoryDeadLetterStore()
+	for i := range maxDeadLetters + 10 {
+		_ = store.Add(DeadLetter{ID: fmt.Sprint(i)})
+	}
+	letters, _ := store.List()
+	if len(letters) != maxDeadLetters || letters[0].ID != "10" {
+		t.Errorf("Expected the newest %d letters, got %d starting at %s", maxDeadLetters, len(letters), letters[0].ID)
+	}
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"a2a/models"
+)
+
+const (
+	// defaultPushMaxAttempts is how many times a notification is tried before it is dead-lettered
+	defaultPushMaxAttempts = 5
+	// defaultPushInitialBackoff is the wait before the first retry; it doubles with every retry
+	defaultPushInitialBackoff = time.Second
+	// defaultPushMaxBackoff caps the wait between retries
+	defaultPushMaxBackoff = time.Minute
+	// maxQueuedNotifications caps the notifications waiting for one task; more are dead-lettered
+	maxQueuedNotifications = 1000
+)
+
+// DeliveryPolicy controls how push notifications are delivered. Zero fields take the defaults.
+type DeliveryPolicy struct {
+	// MaxAttempts is how many times a notification is tried before it is dead-lettered (default 5)
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled for each further one (default 1s)
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries (default 1m)
+	MaxBackoff time.Duration
+	// AllowPrivateAddresses lets notifications reach loopback, private and link-local addresses,
+	// which are refused by default so callers cannot aim the agent at internal services
+	AllowPrivateAddresses bool
+}
+
+// withDefaults fills in the zero fields
+func (p DeliveryPolicy) withDefaults() DeliveryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultPushMaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaultPushInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultPushMaxBackoff
+	}
+	if p.MaxBackoff < p.InitialBackoff {
+		p.MaxBackoff = p.InitialBackoff
+	}
+	return p
+}
+
+// backoff returns the wait after the given failed attempt: exponential, capped, and jittered
+// into its upper half so that receivers coming back up are not hit by every agent at once
+func (p DeliveryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxBackoff
+	if shift := attempt - 1; shift < 32 {
+		if exponential := p.InitialBackoff << shift; exponential > 0 && exponential < delay {
+			delay = exponential
+		}
+	}
+	return delay/2 + mathrand.N(delay/2+1)
+}
+
+// DeliveryMetrics counts what happened to an agent's push notifications since it started
+type DeliveryMetrics struct {
+	// Queued is how many notifications were accepted for delivery
+	Queued int64 `json:"queued"`
+	// Delivered is how many were accepted by their receiver
+	Delivered int64 `json:"delivered"`
+	// Retries is how many attempts were repeats of a failed one
+	Retries int64 `json:"retries"`
+	// DeadLettered is how many were given up on
+	DeadLettered int64 `json:"deadLettered"`
+	// Blocked is how many were refused because their address is not allowed
+	Blocked int64 `json:"blocked"`
+	// Pending is how many are waiting or in flight right now
+	Pending int64 `json:"pending"`
+}
+
+// deliveryCounters backs DeliveryMetrics
+type deliveryCounters struct {
+	queued, delivered, retries, deadLettered, blocked, pending atomic.Int64
+}
+
+// pushDelivery is a notification waiting to be delivered
+type pushDelivery struct {
+	taskID string
+	config models.PushNotificationConfig
+	body   []byte
+}
+
+// deliveryQueue holds a task's notifications in the order they were published. A single
+// goroutine drains it, so a receiver sees a task's events in order. Every notification gets
+// the full retry budget: the last one carries the task's final status, and a receiver that
+// failed on an earlier one may well be back by then.
+type deliveryQueue struct {
+	pending []*pushDelivery
+}
+
+// DeliveryMetrics reports the agent's push notification counters
+func (s *A2AServer) DeliveryMetrics() DeliveryMetrics {
+	stats := &s.deliveryStats
+	return DeliveryMetrics{
+		Queued:       stats.queued.Load(),
+		Delivered:    stats.delivered.Load(),
+		Retries:      stats.retries.Load(),
+		DeadLettered: stats.deadLettered.Load(),
+		Blocked:      stats.blocked.Load(),
+		Pending:      stats.pending.Load(),
+	}
+}
+
+// DeadLetters lists the push notifications the agent gave up on, oldest first
+func (s *A2AServer) DeadLetters() ([]DeadLetter, error) {
+	return s.deadLetters.List()
+}
+
+// DeleteDeadLetter discards a dead letter once it has been dealt with
+func (s *A2AServer) DeleteDeadLetter(id string) error {
+	return s.deadLetters.Delete(id)
+}
+
+// enqueueNotification adds a notification to its task's queue, starting the queue's goroutine
+// when the queue was idle
+func (s *A2AServer) enqueueNotification(delivery *pushDelivery) {
+	s.deliveryStats.queued.Add(1)
+	s.deliveryMu.Lock()
+	queue, exists := s.deliveryQueues[delivery.taskID]
+	if !exists {
+		queue = &deliveryQueue{}
+		s.deliveryQueues[delivery.taskID] = queue
+		go s.drainNotifications(delivery.taskID, queue)
+	}
+	full := len(queue.pending) >= maxQueuedNotifications
+	if !full {
+		queue.pending = append(queue.pending, delivery)
+		s.deliveryStats.pending.Add(1)
+	}
+	s.deliveryMu.Unlock()
+
+	if full {
+		s.deadLetter(delivery, 0, errors.New("delivery queue is full"))
+	}
+}
+
+// drainNotifications delivers a task's notifications one at a time until its queue is empty
+func (s *A2AServer) drainNotifications(taskID string, queue *deliveryQueue) {
+	for {
+		s.deliveryMu.Lock()
+		if len(queue.pending) == 0 {
+			delete(s.deliveryQueues, taskID)
+			if len(s.deliveryQueues) == 0 && s.deliveriesIdle != nil {
+				close(s.deliveriesIdle)
+				s.deliveriesIdle = nil
+			}
+			s.deliveryMu.Unlock()
+			return
+		}
+		delivery := queue.pending[0]
+		queue.pending = queue.pending[1:]
+		s.deliveryMu.Unlock()
+
+		s.deliverNotification(delivery)
+		s.deliveryStats.pending.Add(-1)
+	}
+}
+
+// deliverNotification tries a notification up to the policy's MaxAttempts times, waiting longer
+// after each failure, and dead-letters it if none succeeds
+func (s *A2AServer) deliverNotification(delivery *pushDelivery) {
+	maxAttempts := s.deliveryPolicy.MaxAttempts
+	var err error
+	attempt := 0
+retry:
+	for attempt < maxAttempts {
+		if s.stopCtx.Err() != nil {
+			// Shutdown gave up on delivery before this attempt could be made
+			err = errServerShutdown
+			break
+		}
+		attempt++
+		if attempt > 1 {
+			s.deliveryStats.retries.Add(1)
+		}
+		err = s.postNotification(delivery)
+		if err == nil {
+			s.deliveryStats.delivered.Add(1)
+			return
+		}
+
+		var failed *deliveryError
+		if errors.Is(err, errBlockedAddress) {
+			s.deliveryStats.blocked.Add(1)
+			break
+		}
+		if errors.As(err, &failed) && !failed.retryable() {
+			break
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		wait := s.deliveryPolicy.backoff(attempt)
+		if failed != nil && failed.retryAfter > wait {
+			wait = min(failed.retryAfter, s.deliveryPolicy.MaxBackoff)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.stopCtx.Done():
+			// Give up without counting attempts that were never made
+			timer.Stop()
+			err = errServerShutdown
+			break retry
+		}
+	}
+
+	s.logger.Printf("Giving up on push notification for task %s after %d attempts: %v", delivery.taskID, attempt, err)
+	s.deadLetter(delivery, attempt, err)
+}
+
+// deliveryError is a notification the receiver answered with an unsuccessful status
+type deliveryError struct {
+	status int
+	// retryAfter is the wait the receiver asked for with Retry-After, if any
+	retryAfter time.Duration
+}
+
+func (e *deliveryError) Error() string {
+	return fmt.Sprintf("receiver answered with status %d", e.status)
+}
+
+// retryable reports whether the status says the receiver may accept the notification later
+func (e *deliveryError) retryable() bool {
+	switch e.status {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	default:
+		return e.status >= 500
+	}
+}
+
+// postNotification makes a single delivery attempt. Each attempt is signed afresh, so its
+// signature is never older than the attempt itself.
+func (s *A2AServer) postNotification(delivery *pushDelivery) error {
+	req, err := http.NewRequestWithContext(s.stopCtx, http.MethodPost, delivery.config.URL, bytes.NewReader(delivery.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setPushAuthHeaders(req, &delivery.config)
+	if s.pushSigner != nil {
+		signature, err := s.signNotification(delivery.config.URL, delivery.taskID, delivery.body)
+		if err != nil {
+			return fmt.Errorf("sign notification: %w", err)
+		}
+		req.Header.Set(NotificationSignatureHeader, signature)
+	}
+
+	resp, err := s.pushClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		failed := &deliveryError{status: resp.StatusCode}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			failed.retryAfter = time.Duration(seconds) * time.Second
+		}
+		return failed
+	}
+	return nil
+}
+
+// deadLetter records a notification that could not be delivered
+func (s *A2AServer) deadLetter(delivery *pushDelivery, attempts int, cause error) {
+	s.deliveryStats.deadLettered.Add(1)
+	letter := DeadLetter{
+		ID:       rand.Text(),
+		TaskID:   delivery.taskID,
+		URL:      delivery.config.URL,
+		Event:    json.RawMessage(delivery.body),
+		Attempts: attempts,
+		Error:    cause.Error(),
+		FailedAt: time.Now().UTC(),
+	}
+	if err := s.deadLetters.Add(letter); err != nil {
+		s.logger.Printf("Error recording dead letter for task %s: %v", delivery.taskID, err)
+	}
+}
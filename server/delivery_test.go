@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+// fastRetries retries quickly so that tests do not wait on backoff
+var fastRetries = DeliveryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, AllowPrivateAddresses: true}
+
+// countingHandler publishes the artifacts "1", "2" and "3" before completing the task
+func countingHandler(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+	for _, text := range []string{"1", "2", "3"} {
+		update(models.TaskArtifactUpdateEvent{ID: task.ID, Artifact: models.Artifact{Parts: []models.Part{{Text: stringPtr(text)}}}})
+	}
+	task.Status.State = models.TaskStateCompleted
+	return task, nil
+}
+
+// eventLabel names a notification body by its artifact text or status state
+func eventLabel(t *testing.T, body []byte) string {
+	t.Helper()
+	var event struct {
+		Artifact *models.Artifact `json:"artifact"`
+		Status   *models.TaskStatus
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("Failed to decode notification: %v", err)
+	}
+	if event.Artifact != nil {
+		return *event.Artifact.Parts[0].Text
+	}
+	return string(event.Status.State)
+}
+
+// sendWithPush sends a message to task-1 asking for notifications at url
+func sendWithPush(t *testing.T, server *A2AServer, url string) {
+	t.Helper()
+	response := doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:               "task-1",
+		Message:          models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+		PushNotification: &models.PushNotificationConfig{URL: url},
+	})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+}
+
+func TestA2AServer_PushDeliveryRetriesInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var delivered []string
+	var requests atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every other request fails, so each event needs a retry
+		if requests.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		delivered = append(delivered, eventLabel(t, body))
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	server := NewA2AServer(pushAgentCard(), countingHandler, WithPushDelivery(fastRetries))
+	sendWithPush(t, server, receiver.URL)
+	waitUntil(t, func() bool { return server.DeliveryMetrics().Delivered == 5 })
+
+	mu.Lock()
+	got := strings.Join(delivered, ",")
+	mu.Unlock()
+	if want := "working,1,2,3,completed"; got != want {
+		t.Errorf("Expected events in order %s, got %s", want, got)
+	}
+	metrics := server.DeliveryMetrics()
+	if metrics.Queued != 5 || metrics.Retries != 5 || metrics.DeadLettered != 0 || metrics.Pending != 0 {
+		t.Errorf("Expected 5 events delivered after one retry each, got %+v", metrics)
+	}
+}
+
+func TestA2AServer_PushDeliveryDeadLetters(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		// attempts is how many times each event is tried
+		attempts int
+	}{
+		{"retryable status", http.StatusInternalServerError, 3},
+		{"permanent status", http.StatusBadRequest, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			server := NewA2AServer(pushAgentCard(), mockTaskHandler, WithPushDelivery(fastRetries))
+			sendWithPush(t, server, receiver.URL)
+			waitUntil(t, func() bool { return server.DeliveryMetrics().DeadLettered == 2 })
+
+			letters, err := server.DeadLetters()
+			if err != nil || len(letters) != 2 {
+				t.Fatalf("Expected 2 dead letters, got %d (%v)", len(letters), err)
+			}
+			first := letters[0]
+			if first.TaskID != "task-1" || first.URL != receiver.URL || first.Attempts != tt.attempts || first.ID == "" {
+				t.Errorf("Expected the first event tried %d times, got %+v", tt.attempts, first)
+			}
+			// The final status gets as many tries as the events before it
+			if eventLabel(t, first.Event) != "working" || eventLabel(t, letters[1].Event) != "completed" || letters[1].Attempts != tt.attempts {
+				t.Errorf("Expected the working event first and %d attempts at the final one, got %+v", tt.attempts, letters)
+			}
+			if int(requests.Load()) != 2*tt.attempts {
+				t.Errorf("Expected %d requests, got %d", 2*tt.attempts, requests.Load())
+			}
+
+			if err := server.DeleteDeadLetter(first.ID); err != nil {
+				t.Fatalf("DeleteDeadLetter failed: %v", err)
+			}
+			if err := server.DeleteDeadLetter(first.ID); !errors.Is(err, ErrDeadLetterNotFound) {
+				t.Errorf("Expected ErrDeadLetterNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestA2AServer_PushURLValidation(t *testing.T) {
+	server := NewA2AServer(pushAgentCard(), mockInputTaskHandler)
+	doRPC(t, server, "message/send", sendParams("task-1", "Hello"))
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://hooks.example.com/a2a", true},
+		{"http://203.0.113.7:8443/hook", true},
+		{"http://127.0.0.1:9/hook", false},
+		{"http://localhost/hook", false},
+		{"http://10.1.2.3/hook", false},
+		{"http://192.168.0.10/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[::1]/hook", false},
+		{"ftp://hooks.example.com/a2a", false},
+		{"/relative", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			response := doRPC(t, server, "tasks/pushNotification/set", models.TaskPushNotificationConfig{
+				ID:                     "task-1",
+				PushNotificationConfig: models.PushNotificationConfig{URL: tt.url},
+			})
+			if tt.allowed && response.Error != nil {
+				t.Errorf("Expected the URL to be accepted, got %v", response.Error)
+			}
+			if !tt.allowed && (response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidParams)) {
+				t.Errorf("Expected an invalid params error, got %v", response.Error)
+			}
+		})
+	}
+
+	// Once allowed, loopback receivers can be used
+	allowing := NewA2AServer(pushAgentCard(), mockTaskHandler, allowLoopback)
+	response := doRPC(t, allowing, "message/send", models.TaskSendParams{
+		ID:               "task-1",
+		Message:          models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+		PushNotification: &models.PushNotificationConfig{URL: "http://127.0.0.1:9/hook"},
+	})
+	if response.Error != nil {
+		t.Errorf("Expected loopback to be allowed, got %v", response.Error)
+	}
+}
+
+func TestPushClient_ChecksResolvedAddress(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+	// Names are checked by the address they resolve to, whatever the config check let through
+	named := strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)
+
+	_, err := newPushClient(time.Second, DeliveryPolicy{}).Get(named)
+	if !errors.Is(err, errBlockedAddress) {
+		t.Errorf("Expected the resolved loopback address to be blocked, got %v", err)
+	}
+	resp, err := newPushClient(time.Second, DeliveryPolicy{AllowPrivateAddresses: true}).Get(named)
+	if err != nil {
+		t.Fatalf("Expected the request to be allowed, got %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestA2AServer_ShutdownDeadLettersPendingNotifications(t *testing.T) {
+	var requests atomic.Int64
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	slow := DeliveryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour, AllowPrivateAddresses: true}
+	server := NewA2AServer(pushAgentCard(), mockTaskHandler, WithPushDelivery(slow))
+	sendWithPush(t, server, receiver.URL)
+	waitUntil(t, func() bool { return server.DeliveryMetrics().Queued == 2 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected Shutdown to give up on the notifications, got %v", err)
+	}
+	waitUntil(t, func() bool { return server.DeliveryMetrics().DeadLettered == 2 })
+	letters, _ := server.DeadLetters()
+	if !strings.Contains(letters[0].Error, errServerShutdown.Error()) {
+		t.Errorf("Expected the shutdown to be recorded, got %q", letters[0].Error)
+	}
+	// Only the attempts actually made are recorded
+	if letters[0].Attempts != 1 || letters[1].Attempts != 0 || requests.Load() != 1 {
+		t.Errorf("Expected 1 and 0 attempts for 1 request, got %d and %d for %d", letters[0].Attempts, letters[1].Attempts, requests.Load())
+	}
+}
+
+func TestDeliveryPolicy_Backoff(t *testing.T) {
+	policy := DeliveryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}.withDefaults()
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 80: time.Second} {
+		for range 20 {
+			if got := policy.backoff(attempt); got < want/2 || got > want {
+				t.Errorf("backoff(%d) = %v, want within [%v, %v]", attempt, got, want/2, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"a2a/models"
+)
+
+// Error is a JSON-RPC error carrying an A2A error code. Handlers return one, possibly wrapped,
+// to choose the code, message and data the client receives; any other error is reported to the
+// client as an internal error.
+type Error struct {
+	Code    models.ErrorCode
+	Message string
+	// Data is optional detail about the error, such as which field failed validation
+	Data any
+}
+
+// NewError creates an error with the given code and message
+func NewError(code models.ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// WithData returns a copy of the error carrying data
+func (e *Error) WithData(data any) *Error {
+	copied := *e
+	copied.Data = data
+	return &copied
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// toRPCError maps an error to the JSON-RPC error sent to the client
+func toRPCError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	var transitionErr *transitionError
+	if errors.As(err, &transitionErr) {
+		return NewError(models.ErrorCodeUnsupportedOperation, transitionErr.Error())
+	}
+	if errors.Is(err, ErrTaskNotFound) {
+		return NewError(models.ErrorCodeTaskNotFound, "Task not found")
+	}
+	return NewError(models.ErrorCodeInternalError, err.Error())
+}
+
+// decodeParams reads the request's params into v, which must point to one of the task params
+// types, and checks that they name a task and that any message parts are well formed
+func decodeParams(req *models.JSONRPCRequest, v any) error {
+	paramsBytes, err := json.Marshal(req.Params)
+	if err != nil {
+		return NewError(models.ErrorCodeInvalidParams, "Invalid parameters").WithData(err.Error())
+	}
+	if err := json.Unmarshal(paramsBytes, v); err != nil {
+		return NewError(models.ErrorCodeInvalidParams, "Invalid parameters").WithData(err.Error())
+	}
+
+	var taskID string
+	switch params := v.(type) {
+	case *models.TaskSendParams:
+		taskID = params.ID
+	case *models.TaskQueryParams:
+		taskID = params.ID
+	case *models.TaskIDParams:
+		taskID = params.ID
+	case *models.TaskPushNotificationConfig:
+		taskID = params.ID
+	}
+	if taskID == "" {
+		return NewError(models.ErrorCodeInvalidParams, "Task id is required").WithData(map[string]any{"field": "id"})
+	}
+	if params, ok := v.(*models.TaskSendParams); ok {
+		for i, part := range params.Message.Parts {
+			if err := part.Validate(); err != nil {
+				return NewError(models.ErrorCodeInvalidParams, "Invalid message part").WithData(map[string]any{"part": i, "reason": err.Error()})
+			}
+		}
+	}
+	return nil
+}
+
+// sendRPCError reports err to the client as a JSON-RPC error
+func (s *A2AServer) sendRPCError(w http.ResponseWriter, id json.RawMessage, err error) {
+	s.writeError(w, id, toRPCError(err))
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"a2a/models"
+)
+
+// rpcResult is a JSON-RPC response with the error data kept as raw JSON
+type rpcResult struct {
+	Error *struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	} `json:"error"`
+}
+
+func TestA2AServer_HandlerTypedError(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		err := NewError(models.ErrorCodeUnsupportedOperation, "Only text parts are supported").WithData(map[string]any{"part": 0})
+		return nil, fmt.Errorf("check parts: %w", err)
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+	send := `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"id":"test-task-1","message":{"role":"user","parts":[{"text":"hi"}]}}}`
+
+	w := postRaw(server, send)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var response rpcResult
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeUnsupportedOperation) {
+		t.Fatalf("Expected unsupported operation error, got %+v", response.Error)
+	}
+	if response.Error.Message != "Only text parts are supported" || string(response.Error.Data) != `{"part":0}` {
+		t.Errorf("Expected the handler's message and data, got %q %s", response.Error.Message, response.Error.Data)
+	}
+
+	task, err := server.store.Get("test-task-1")
+	if err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	if task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateFailed, task.Status.State)
+	}
+
+	// Streaming clients get the same error as an event
+	w = postRaw(server, `{"jsonrpc":"2.0","id":2,"method":"message/stream","params":{"id":"test-task-2","message":{"role":"user","parts":[{"text":"hi"}]}}}`)
+	data := streamData(t, w.Body.String())
+	if len(data) < 2 {
+		t.Fatalf("Expected stream events, got %q", w.Body.String())
+	}
+	var event rpcResult
+	if err := json.Unmarshal([]byte(data[len(data)-2]), &event); err != nil {
+		t.Fatalf("Failed to decode error event: %v", err)
+	}
+	if event.Error == nil || event.Error.Code != int(models.ErrorCodeUnsupportedOperation) || string(event.Error.Data) != `{"part":0}` {
+		t.Errorf("Expected unsupported operation error event with data, got %+v", event.Error)
+	}
+}
+
+func TestA2AServer_ErrorCodes(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	tests := []struct {
+		name string
+		body string
+		code models.ErrorCode
+		data string
+	}{
+		{"malformed json", `{"jsonrpc":`, models.ErrorCodeParseError, ""},
+		{"not an object", `"tasks/get"`, models.ErrorCodeInvalidRequest, ""},
+		{"missing version", `{"id":1,"method":"tasks/get","params":{"id":"x"}}`, models.ErrorCodeInvalidRequest, ""},
+		{"missing method", `{"jsonrpc":"2.0","id":1}`, models.ErrorCodeInvalidRequest, ""},
+		{"unknown method", `{"jsonrpc":"2.0","id":1,"method":"tasks/list"}`, models.ErrorCodeMethodNotFound, ""},
+		{"wrong param type", `{"jsonrpc":"2.0","id":1,"method":"tasks/get","params":{"id":5}}`, models.ErrorCodeInvalidParams, ""},
+		{"missing task id", `{"jsonrpc":"2.0","id":1,"method":"tasks/cancel","params":{}}`, models.ErrorCodeInvalidParams, `{"field":"id"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postRaw(server, tt.body)
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			var response rpcResult
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response %q: %v", w.Body.String(), err)
+			}
+			if response.Error == nil || response.Error.Code != int(tt.code) {
+				t.Fatalf("Expected error %d, got %+v", tt.code, response.Error)
+			}
+			if tt.data != "" && string(response.Error.Data) != tt.data {
+				t.Errorf("Expected data %s, got %s", tt.data, response.Error.Data)
+			}
+		})
+	}
+}
+
+func TestA2AServer_MethodNotAllowed(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if w.Header().Get("Allow") != "GET, POST" {
+		t.Errorf("Expected Allow header, got %q", w.Header().Get("Allow"))
+	}
+	var response rpcResult
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Error == nil {
+		t.Fatalf("Expected a JSON-RPC error body, got %q", w.Body.String())
+	}
+}
+
+func TestA2AServer_FilePart(t *testing.T) {
+	var received models.FileContent
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		received = message.Parts[0].File
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	w := postRaw(server, `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"id":"test-task-1","message":{"role":"user","parts":[{"type":"file","file":{"name":"receipt.txt","bytes":"aGVsbG8="}}]}}}`)
+	var response rpcResult
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Error != nil {
+		t.Fatalf("Expected success, got %q", w.Body.String())
+	}
+	if file, ok := received.(models.FileContentBytes); !ok || file.Bytes != "aGVsbG8=" {
+		t.Errorf("Expected the handler to get the file bytes, got %#v", received)
+	}
+
+	// A part without content is rejected before it reaches the handler
+	w = postRaw(server, `{"jsonrpc":"2.0","id":2,"method":"message/send","params":{"id":"test-task-2","message":{"role":"user","parts":[{"metadata":{}}]}}}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidParams) {
+		t.Errorf("Expected invalid params error, got %+v", response.Error)
+	}
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"a2a/models"
+)
+
+const (
+	// maxTaskEvents caps how many events are kept per task run for replay to resubscribing clients
+	maxTaskEvents = 4096
+	// defaultHeartbeatInterval is how often an idle stream gets a comment line to keep it open
+	defaultHeartbeatInterval = 15 * time.Second
+	// defaultEventLogRetention is how long a finished run's events stay available to clients
+	// reconnecting with tasks/resubscribe before the log is dropped
+	defaultEventLogRetention = 5 * time.Minute
+)
+
+// eventLog records the events of a single task run and wakes up readers when new ones arrive.
+// Events are addressed by a sequence number that keeps increasing even after old events are
+// dropped, so a reader's cursor stays valid for the whole run.
+type eventLog struct {
+	mu      sync.Mutex
+	events  []any
+	dropped int
+	closed  bool
+	wake    chan struct{}
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{wake: make(chan struct{})}
+}
+
+// append records an event and notifies waiting readers
+func (l *eventLog) append(event any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+	l.events = append(l.events, event)
+	if len(l.events) > maxTaskEvents {
+		l.events = l.events[1:]
+		l.dropped++
+	}
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// close marks the run as finished; readers drain what is left and stop
+func (l *eventLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+	l.closed = true
+	close(l.wake)
+}
+
+// since returns the events from sequence number from onwards, the sequence number following
+// the last returned event, whether the log is closed, and a channel that is closed on the next change
+func (l *eventLog) since(from int) ([]any, int, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if from < l.dropped {
+		from = l.dropped
+	}
+	start := from - l.dropped
+	if start > len(l.events) {
+		start = len(l.events)
+	}
+	events := make([]any, len(l.events)-start)
+	copy(events, l.events[start:])
+	return events, l.dropped + len(l.events), l.closed, l.wake
+}
+
+// startEventLog begins a fresh event log for a new run of a task, closing any previous one
+func (s *A2AServer) startEventLog(taskID string) *eventLog {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+
+	if previous, exists := s.eventLogs[taskID]; exists {
+		previous.close()
+	}
+	log := newEventLog()
+	s.eventLogs[taskID] = log
+	return log
+}
+
+// endEventLog closes the log of a finished run and drops it once the retention period is over,
+// unless a newer run of the task has replaced it by then. Resubscribing after that gets the
+// task's current status instead of a replay.
+func (s *A2AServer) endEventLog(taskID string, log *eventLog) {
+	log.close()
+	time.AfterFunc(s.eventLogRetention, func() {
+		s.eventsMu.Lock()
+		defer s.eventsMu.Unlock()
+		if s.eventLogs[taskID] == log {
+			delete(s.eventLogs, taskID)
+		}
+	})
+}
+
+// getEventLog returns the event log of the latest run of a task, if any
+func (s *A2AServer) getEventLog(taskID string) (*eventLog, bool) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	log, exists := s.eventLogs[taskID]
+	return log, exists
+}
+
+// publishError records a failed run for streaming subscribers; it is sent to them as a
+// JSON-RPC error event. Push notification endpoints only receive task events.
+func (s *A2AServer) publishError(log *eventLog, err error) {
+	rpcErr := toRPCError(err)
+	log.append(&models.A2AError{
+		JSONRPCError: models.JSONRPCError{Message: rpcErr.Message, Data: rpcErr.Data},
+		Code:         rpcErr.Code,
+	})
+}
+
+// publish records a task event for streaming subscribers and forwards it to the push notification endpoint
+func (s *A2AServer) publish(log *eventLog, taskID string, event any) {
+	log.append(event)
+	s.sendPushNotification(taskID, event)
+}
+
+// setSSEHeaders prepares the response for a Server-Sent Events stream
+func setSSEHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+}
+
+// lastEventID returns the sequence number to resume a stream from, taken from the
+// Last-Event-ID header a reconnecting client sends, and whether the header was usable
+func lastEventID(r *http.Request) (int, bool) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		return 0, false
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 0 {
+		return 0, false
+	}
+	// Event IDs are one past the sequence number, so the last seen ID is where to continue
+	return id, true
+}
+
+// streamEvents writes events from the log to the client as SSE frames, starting at sequence
+// number from, until the run is finished or the client goes away. Every event is a JSON-RPC
+// response carrying the id of the request that opened the stream, and each frame's ID is the
+// event's sequence number plus one. Idle streams get a comment line every heartbeat interval.
+func (s *A2AServer) streamEvents(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, log *eventLog, from int, id json.RawMessage) {
+	heartbeat := time.NewTicker(s.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		events, next, closed, wake := log.since(from)
+		first := next - len(events)
+		for i, event := range events {
+			resp := models.SendTaskStreamingResponse{
+				JSONRPCResponse: models.JSONRPCResponse{
+					JSONRPCMessage: models.JSONRPCMessage{
+						JSONRPC: "2.0",
+						JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+							ID: id,
+						},
+					},
+				},
+			}
+			if rpcErr, ok := event.(*models.A2AError); ok {
+				resp.Error = rpcErr
+			} else {
+				resp.Result = event
+			}
+			data, err := json.Marshal(resp)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", first+i+1, data); err != nil {
+				return
+			}
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+		}
+		from = next
+
+		if closed {
+			return
+		}
+
+		select {
+		case <-wake:
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ctx.Done():
+			// Client disconnected; the task keeps running and can be resubscribed to
+			return
+		}
+	}
+}
+
+// recordArtifact applies the artifact carried by a handler update, if any, to the running task
+func recordArtifact(task *models.Task, event any) {
+	switch e := event.(type) {
+	case models.TaskArtifactUpdateEvent:
+		applyArtifact(task, e.Artifact)
+	case *models.TaskArtifactUpdateEvent:
+		applyArtifact(task, e.Artifact)
+	}
+}
+
+// checkUpdate rejects a handler update carrying parts that could not be read back once stored,
+// such as a part with no content: recording it would leave the task store unable to load
+func checkUpdate(event any) error {
+	switch e := event.(type) {
+	case models.TaskArtifactUpdateEvent:
+		return checkParts("artifact", e.Artifact.Parts)
+	case *models.TaskArtifactUpdateEvent:
+		return checkParts("artifact", e.Artifact.Parts)
+	case models.TaskStatusUpdateEvent:
+		return checkStatusMessage(e.Status)
+	case *models.TaskStatusUpdateEvent:
+		return checkStatusMessage(e.Status)
+	}
+	return nil
+}
+
+// checkStatusMessage checks the parts of the message a status carries, if any
+func checkStatusMessage(status models.TaskStatus) error {
+	if status.Message == nil {
+		return nil
+	}
+	return checkParts("status message", status.Message.Parts)
+}
+
+// checkParts reports the first part that is not well formed
+func checkParts(what string, parts []models.Part) error {
+	for i, part := range parts {
+		if err := part.Validate(); err != nil {
+			return fmt.Errorf("handler produced an invalid %s part %d: %w", what, i, err)
+		}
+	}
+	return nil
+}
+
+// keepArtifacts carries artifacts streamed during a run over to the task returned by the
+// handler, in case the handler built a fresh task instead of updating the one it was given
+func keepArtifacts(running, returned *models.Task) {
+	if returned != running && len(returned.Artifacts) == 0 {
+		returned.Artifacts = running.Artifacts
+	}
+}
+
+// applyArtifact merges an artifact update into the task. Chunks flagged with Append are added
+// to the artifact with the same index (or the latest one when no index is given); anything else
+// is recorded as a new artifact.
+func applyArtifact(task *models.Task, artifact models.Artifact) {
+	if artifact.Append != nil && *artifact.Append && len(task.Artifacts) > 0 {
+		target := len(task.Artifacts) - 1
+		if artifact.Index != nil {
+			target = -1
+			for i, existing := range task.Artifacts {
+				if existing.Index != nil && *existing.Index == *artifact.Index {
+					target = i
+					break
+				}
+			}
+		}
+		if target >= 0 {
+			existing := &task.Artifacts[target]
+			existing.Parts = append(existing.Parts, artifact.Parts...)
+			existing.LastChunk = artifact.LastChunk
+			return
+		}
+	}
+	task.Artifacts = append(task.Artifacts, artifact)
+}
@@ -0,0 +1,380 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+// sseFrame is one event of a text/event-stream body
+type sseFrame struct {
+	ID   string
+	Data string
+}
+
+// parseSSE splits a text/event-stream body into its events, skipping comment lines
+func parseSSE(t *testing.T, body string) []sseFrame {
+	t.Helper()
+
+	var frames []sseFrame
+	for _, block := range strings.Split(body, "\n\n") {
+		var frame sseFrame
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case line == "" || strings.HasPrefix(line, ":"):
+			case strings.HasPrefix(line, "id: "):
+				frame.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				frame.Data += strings.TrimPrefix(line, "data: ")
+			default:
+				t.Fatalf("Unexpected stream line %q", line)
+			}
+		}
+		if frame.Data != "" {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+// streamData returns the JSON payloads of a text/event-stream body
+func streamData(t *testing.T, body string) []string {
+	t.Helper()
+
+	var data []string
+	for _, frame := range parseSSE(t, body) {
+		data = append(data, frame.Data)
+	}
+	return data
+}
+
+// decodeStreamEvents parses a text/event-stream body into generic events
+func decodeStreamEvents(t *testing.T, body string) []map[string]any {
+	t.Helper()
+
+	var events []map[string]any
+	for _, data := range streamData(t, body) {
+		var resp models.SendTaskStreamingResponse
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal stream event %q: %v", data, err)
+		}
+		event, _ := resp.Result.(map[string]any)
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestA2AServer_ResubscribeAfterDisconnect(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		update(models.TaskArtifactUpdateEvent{
+			ID:       task.ID,
+			Artifact: models.Artifact{Parts: []models.Part{{Text: stringPtr("part-1")}}},
+		})
+		close(started)
+		<-release
+		update(models.TaskArtifactUpdateEvent{
+			ID:       task.ID,
+			Artifact: models.Artifact{Parts: []models.Part{{Text: stringPtr("part-2")}}},
+		})
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC:                  "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "1"},
+		},
+		Method: "message/stream",
+		Params: models.TaskSendParams{
+			ID:      "test-task-1",
+			Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+		},
+	})
+
+	// Open the stream, then drop the connection while the handler is still running
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	streamDone := make(chan struct{})
+	go func() {
+		server.ServeHTTP(w, req)
+		close(streamDone)
+	}()
+	<-started
+	cancel()
+	<-streamDone
+
+	// Reattach and let the handler finish
+	reqBody, _ = json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC:                  "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{ID: "2"},
+		},
+		Method: "tasks/resubscribe",
+		Params: models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task-1"}},
+	})
+	req = httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	w = httptest.NewRecorder()
+	resubDone := make(chan struct{})
+	go func() {
+		server.ServeHTTP(w, req)
+		close(resubDone)
+	}()
+	close(release)
+
+	select {
+	case <-resubDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for resubscribed stream to finish")
+	}
+
+	events := decodeStreamEvents(t, w.Body.String())
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 replayed and live events, got %d: %s", len(events), w.Body.String())
+	}
+
+	// Replayed events are addressed to the resubscribe request, not the original one
+	for _, data := range streamData(t, w.Body.String()) {
+		var resp models.SendTaskStreamingResponse
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal stream event: %v", err)
+		}
+		if resp.JSONRPC != "2.0" || resp.ID != "2" {
+			t.Errorf("Expected events for request 2, got jsonrpc %q id %v", resp.JSONRPC, resp.ID)
+		}
+	}
+
+	var texts []string
+	for _, event := range events[1:3] {
+		artifact, _ := event["artifact"].(map[string]any)
+		parts, _ := artifact["parts"].([]any)
+		part, _ := parts[0].(map[string]any)
+		text, _ := part["text"].(string)
+		texts = append(texts, text)
+	}
+	if strings.Join(texts, ",") != "part-1,part-2" {
+		t.Errorf("Expected artifacts part-1,part-2, got %v", texts)
+	}
+
+	status, _ := events[3]["status"].(map[string]any)
+	if status["state"] != string(models.TaskStateCompleted) || events[3]["final"] != true {
+		t.Errorf("Expected final completed status, got %v", events[3])
+	}
+}
+
+func TestA2AServer_ResubscribeTaskNotFound(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	response := doRPC(t, server, "tasks/resubscribe", models.TaskQueryParams{
+		TaskIDParams: models.TaskIDParams{ID: "missing"},
+	})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Fatalf("Expected task not found error, got %v", response.Error)
+	}
+}
+
+func TestA2AServer_EventLogEviction(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	server.eventLogRetention = 20 * time.Millisecond
+
+	doRPC(t, server, "message/send", sendParams("test-task-1", "Hello"))
+	if _, exists := server.getEventLog("test-task-1"); !exists {
+		t.Fatal("Expected the finished run's events to be kept for resubscribing clients")
+	}
+	waitUntil(t, func() bool {
+		_, exists := server.getEventLog("test-task-1")
+		return !exists
+	})
+
+	// Resubscribing afterwards reports the task's current status
+	req := newRPCRequest("tasks/resubscribe", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task-1"}})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if body := w.Body.String(); !strings.Contains(body, `"final":true`) || !strings.Contains(body, string(models.TaskStateCompleted)) {
+		t.Errorf("Expected the final status after eviction, got %q", body)
+	}
+}
+
+func TestA2AServer_RecordsArtifacts(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		for i, chunk := range []string{"Hel", "lo"} {
+			update(models.TaskArtifactUpdateEvent{
+				ID: task.ID,
+				Artifact: models.Artifact{
+					Parts:  []models.Part{{Text: stringPtr(chunk)}},
+					Index:  intPtr(0),
+					Append: boolPtr(i > 0),
+				},
+			})
+		}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	response := doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+	})
+
+	var task models.Task
+	decodeResult(t, response.Result, &task)
+	if len(task.Artifacts) != 1 {
+		t.Fatalf("Expected chunks to merge into 1 artifact, got %d", len(task.Artifacts))
+	}
+	if parts := task.Artifacts[0].Parts; len(parts) != 2 || *parts[0].Text+*parts[1].Text != "Hello" {
+		t.Errorf("Expected merged parts Hel+lo, got %v", parts)
+	}
+}
+
+func TestA2AServer_StreamFraming(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		for _, chunk := range []string{"part-1", "part-2"} {
+			update(models.TaskArtifactUpdateEvent{
+				ID:       task.ID,
+				Artifact: models.Artifact{Parts: []models.Part{{Text: stringPtr(chunk)}}},
+			})
+		}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+	params := models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, newRPCRequest("message/stream", params))
+
+	frames := parseSSE(t, w.Body.String())
+	var ids []string
+	for _, frame := range frames {
+		ids = append(ids, frame.ID)
+	}
+	if strings.Join(ids, ",") != "1,2,3,4" {
+		t.Fatalf("Expected event IDs 1,2,3,4, got %v", ids)
+	}
+	if !strings.HasSuffix(w.Body.String(), "\n\n") {
+		t.Errorf("Expected the stream to end with a blank line, got %q", w.Body.String())
+	}
+
+	// Resubscribing with Last-Event-ID only replays what the client has not seen
+	req := newRPCRequest("tasks/resubscribe", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task-1"}})
+	req.Header.Set("Last-Event-ID", "2")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	frames = parseSSE(t, w.Body.String())
+	if len(frames) != 2 || frames[0].ID != "3" || frames[1].ID != "4" {
+		t.Fatalf("Expected events 3 and 4, got %v", frames)
+	}
+}
+
+func TestA2AServer_StreamIgnoresLastEventID(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockInputTaskHandler)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, newRPCRequest("message/stream", sendParams("test-task-1", "hello")))
+	before, err := server.store.History("test-task-1")
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+
+	// A follow-up message is processed even when the client sends a Last-Event-ID
+	req := newRPCRequest("message/stream", sendParams("test-task-1", "again"))
+	req.Header.Set("Last-Event-ID", "1")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	frames := parseSSE(t, w.Body.String())
+	if len(frames) == 0 || frames[0].ID != "1" {
+		t.Fatalf("Expected a new run starting at event 1, got %v", frames)
+	}
+	after, err := server.store.History("test-task-1")
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(after) <= len(before) {
+		t.Errorf("Expected the follow-up message in history, got %d entries before and %d after", len(before), len(after))
+	}
+}
+
+func TestA2AServer_StreamLockCanceled(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	unlock, err := server.taskLocks.lock(context.Background(), "test-task-1")
+	if err != nil {
+		t.Fatalf("Failed to take the task lock: %v", err)
+	}
+	defer unlock()
+
+	// The client gives up while an earlier message still holds the task
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, newRPCRequest("message/stream", sendParams("test-task-1", "hello")).WithContext(ctx))
+
+	var response models.JSONRPCResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected a JSON-RPC response, got %q: %v", w.Body.String(), err)
+	}
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Errorf("Expected an internal error, got %v", response.Error)
+	}
+}
+
+func TestA2AServer_StreamHeartbeat(t *testing.T) {
+	release := make(chan struct{})
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		<-release
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	httpServer := httptest.NewServer(NewA2AServer(mockAgentCard, handler, WithHeartbeatInterval(10*time.Millisecond)))
+	defer httpServer.Close()
+
+	req := newRPCRequest("message/stream", models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+	})
+	resp, err := http.Post(httpServer.URL, "application/json", req.Body)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// The handler is idle, so a keepalive comment has to arrive before anything else ends the stream
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			close(release)
+			t.Fatalf("Stream ended before a heartbeat: %v", err)
+		}
+		if line == ": keepalive\n" {
+			break
+		}
+	}
+	close(release)
+
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read stream: %v", err)
+	}
+	events := decodeStreamEvents(t, string(rest))
+	if len(events) == 0 || events[len(events)-1]["final"] != true {
+		t.Errorf("Expected the stream to finish with the final event, got %s", rest)
+	}
+}
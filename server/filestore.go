@@ -0,0 +1,302 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"a2a/models"
+)
+
+const (
+	// fileStoreLogName is the append-only log holding every change made to the store
+	fileStoreLogName = "tasks.log"
+	// defaultCompactThreshold is how many records may be appended before the log is compacted
+	defaultCompactThreshold = 1000
+)
+
+// fileStoreRecord is a single entry in the append-only log
+type fileStoreRecord struct {
+	// Op is one of "put", "history" or "delete"
+	Op      string          `json:"op"`
+	ID      string          `json:"id"`
+	Task    *models.Task    `json:"task,omitempty"`
+	Message *models.Message `json:"message,omitempty"`
+}
+
+// FileTaskStore is a TaskStore that survives restarts. Every change is appended to a log file
+// in its directory and synced to disk before the call returns; the log is replayed on open and
+// periodically rewritten to contain only the live state.
+type FileTaskStore struct {
+	dir       string
+	file      *os.File
+	memory    *MemoryTaskStore
+	appended  int
+	live      int
+	threshold int
+	mu        sync.Mutex
+}
+
+// NewFileTaskStore opens (or creates) a file-backed store in dir and rebuilds its state from the log
+func NewFileTaskStore(dir string) (*FileTaskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create store directory: %w", err)
+	}
+
+	f := &FileTaskStore{
+		dir:       dir,
+		memory:    NewMemoryTaskStore(),
+		threshold: defaultCompactThreshold,
+	}
+	if err := f.replay(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(f.logPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open task log: %w", err)
+	}
+	f.file = file
+	return f, nil
+}
+
+func (f *FileTaskStore) logPath() string {
+	return filepath.Join(f.dir, fileStoreLogName)
+}
+
+// replay rebuilds the in-memory state from the log. A torn record at the end of the log, left
+// behind by a crash in the middle of a write, has no trailing newline; it is cut off so that new
+// records start cleanly. A complete record that cannot be read means the log is damaged, and
+// replay fails rather than throw away the records that follow it.
+func (f *FileTaskStore) replay() error {
+	file, err := os.OpenFile(f.logPath(), os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open task log: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := bufio.NewReader(file)
+	var valid int64
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Anything after the last newline is an incomplete record
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read task log: %w", err)
+		}
+
+		var record fileStoreRecord
+		if err := json.Unmarshal(bytes.TrimSpace(line), &record); err != nil {
+			return fmt.Errorf("task log %s line %d is corrupt: %w", f.logPath(), lineNumber, err)
+		}
+		if err := f.apply(record); err != nil {
+			return err
+		}
+		valid += int64(len(line))
+		f.appended++
+	}
+
+	if err := file.Truncate(valid); err != nil {
+		return fmt.Errorf("truncate task log: %w", err)
+	}
+	return nil
+}
+
+// apply performs a logged change on the in-memory state
+func (f *FileTaskStore) apply(record fileStoreRecord) error {
+	switch record.Op {
+	case "put":
+		if record.Task == nil {
+			return fmt.Errorf("task log: put record for %q has no task", record.ID)
+		}
+		return f.memory.Put(record.Task)
+	case "history":
+		if record.Message == nil {
+			return fmt.Errorf("task log: history record for %q has no message", record.ID)
+		}
+		return f.memory.AppendHistory(record.ID, record.Message)
+	case "delete":
+		if err := f.memory.Delete(record.ID); err != nil && !errors.Is(err, ErrTaskNotFound) {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("task log: unknown operation %q", record.Op)
+	}
+}
+
+// write appends a record to the log, syncs it, and applies it to the in-memory state.
+// Must be called with f.mu held.
+func (f *FileTaskStore) write(record fileStoreRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode task log record: %w", err)
+	}
+	line = append(line, '\n')
+
+	if _, err := f.file.Write(line); err != nil {
+		return fmt.Errorf("write task log: %w", err)
+	}
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("sync task log: %w", err)
+	}
+	if err := f.apply(record); err != nil {
+		return err
+	}
+
+	f.appended++
+	// Compact once the log has grown well past the live state, so a large live
+	// state does not trigger a compaction on every write
+	if f.appended >= f.threshold && f.appended >= 2*f.live {
+		return f.compact()
+	}
+	return nil
+}
+
+// Get returns the task with the given ID
+func (f *FileTaskStore) Get(id string) (*models.Task, error) {
+	return f.memory.Get(id)
+}
+
+// Put durably creates or replaces a task
+func (f *FileTaskStore) Put(task *models.Task) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.write(fileStoreRecord{Op: "put", ID: task.ID, Task: task})
+}
+
+// AppendHistory durably appends a message to the task's history
+func (f *FileTaskStore) AppendHistory(id string, message *models.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.write(fileStoreRecord{Op: "history", ID: id, Message: message})
+}
+
+// History returns the messages recorded for a task
+func (f *FileTaskStore) History(id string) ([]*models.Message, error) {
+	return f.memory.History(id)
+}
+
+// List returns all stored tasks ordered by ID
+func (f *FileTaskStore) List() ([]*models.Task, error) {
+	return f.memory.List()
+}
+
+// Delete durably removes a task and its history
+func (f *FileTaskStore) Delete(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.memory.Get(id); err != nil {
+		return err
+	}
+	return f.write(fileStoreRecord{Op: "delete", ID: id})
+}
+
+// Compact rewrites the log so it holds only the current tasks and their history
+func (f *FileTaskStore) Compact() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.compact()
+}
+
+// compact writes the live state to a temporary file and atomically swaps it in for the log.
+// Must be called with f.mu held.
+func (f *FileTaskStore) compact() error {
+	tasks, err := f.memory.List()
+	if err != nil {
+		return err
+	}
+
+	tmpPath := f.logPath() + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("create compacted log: %w", err)
+	}
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	records := 0
+	for _, task := range tasks {
+		if err := encoder.Encode(fileStoreRecord{Op: "put", ID: task.ID, Task: task}); err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("write compacted log: %w", err)
+		}
+		records++
+
+		history, err := f.memory.History(task.ID)
+		if err != nil {
+			_ = tmp.Close()
+			return err
+		}
+		for _, message := range history {
+			if err := encoder.Encode(fileStoreRecord{Op: "history", ID: task.ID, Message: message}); err != nil {
+				_ = tmp.Close()
+				return fmt.Errorf("write compacted log: %w", err)
+			}
+			records++
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write compacted log: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("sync compacted log: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close compacted log: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, f.logPath()); err != nil {
+		return fmt.Errorf("replace task log: %w", err)
+	}
+	if err := syncDir(f.dir); err != nil {
+		return err
+	}
+
+	// Reopen so further appends go to the compacted file
+	file, err := os.OpenFile(f.logPath(), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("reopen task log: %w", err)
+	}
+	_ = f.file.Close()
+	f.file = file
+
+	f.appended = records
+	f.live = records
+	return nil
+}
+
+// Close releases the log file
+func (f *FileTaskStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// syncDir makes a rename within dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open store directory: %w", err)
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync store directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"a2a/models"
+)
+
+func TestFileTaskStore_Reopen(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	_ = store.Put(&models.Task{
+		ID:        "task-1",
+		Status:    models.TaskStatus{State: models.TaskStateCompleted},
+		Artifacts: []models.Artifact{{Parts: []models.Part{{Text: stringPtr("report")}}}},
+	})
+	_ = store.AppendHistory("task-1", &models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}})
+	_ = store.Put(&models.Task{ID: "task-2"})
+	_ = store.Delete("task-2")
+	_ = store.Close()
+
+	store, err = NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	task, err := store.Get("task-1")
+	if err != nil {
+		t.Fatalf("Expected task to survive reopen, got %v", err)
+	}
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCompleted, task.Status.State)
+	}
+	if len(task.Artifacts) != 1 || *task.Artifacts[0].Parts[0].Text != "report" {
+		t.Errorf("Expected artifact to survive reopen, got %v", task.Artifacts)
+	}
+	history, _ := store.History("task-1")
+	if len(history) != 1 || *history[0].Parts[0].Text != "Hello" {
+		t.Errorf("Expected history to survive reopen, got %v", history)
+	}
+	if _, err := store.Get("task-2"); err == nil {
+		t.Error("Expected deleted task to stay deleted")
+	}
+}
+
+func TestFileTaskStore_TornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	_ = store.Put(&models.Task{ID: "task-1", Status: models.TaskStatus{State: models.TaskStateCompleted}})
+	_ = store.Close()
+
+	// Simulate a crash halfway through writing the next record
+	logFile, _ := os.OpenFile(filepath.Join(dir, fileStoreLogName), os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = logFile.WriteString(`{"op":"put","id":"task-2","ta`)
+	_ = logFile.Close()
+
+	store, err = NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store after torn write: %v", err)
+	}
+	if _, err := store.Get("task-1"); err != nil {
+		t.Errorf("Expected intact task to be recovered, got %v", err)
+	}
+	_ = store.Put(&models.Task{ID: "task-3"})
+	_ = store.Close()
+
+	// The torn tail must not corrupt records written after recovery
+	store, err = NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	if _, err := store.Get("task-3"); err != nil {
+		t.Errorf("Expected task written after recovery, got %v", err)
+	}
+}
+
+func TestFileTaskStore_CorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	_ = store.Put(&models.Task{ID: "task-1"})
+	_ = store.Close()
+
+	// A complete but unreadable record in the middle of the log, followed by a valid one
+	logPath := filepath.Join(dir, fileStoreLogName)
+	logFile, _ := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = logFile.WriteString("{\"op\":\"put\",\"id\":\"task-2\",\"task\":[]}\n")
+	_, _ = logFile.WriteString("{\"op\":\"put\",\"id\":\"task-3\",\"task\":{\"id\":\"task-3\"}}\n")
+	_ = logFile.Close()
+	before, _ := os.ReadFile(logPath)
+
+	if _, err := NewFileTaskStore(dir); err == nil {
+		t.Fatal("Expected a corrupt record to make opening the store fail")
+	}
+	after, _ := os.ReadFile(logPath)
+	if string(after) != string(before) {
+		t.Error("Expected the log to be left untouched, including the records after the corrupt one")
+	}
+}
+
+func TestFileTaskStore_Compact(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	for _, state := range []models.TaskState{models.TaskStateSubmitted, models.TaskStateWorking, models.TaskStateCompleted} {
+		_ = store.Put(&models.Task{ID: "task-1", Status: models.TaskStatus{State: state}})
+	}
+	_ = store.AppendHistory("task-1", &models.Message{Role: "user"})
+
+	before, _ := os.Stat(filepath.Join(dir, fileStoreLogName))
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	after, _ := os.Stat(filepath.Join(dir, fileStoreLogName))
+	if after.Size() >= before.Size() {
+		t.Errorf("Expected compaction to shrink the log, got %d -> %d bytes", before.Size(), after.Size())
+	}
+	_ = store.Close()
+
+	store, err = NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	task, err := store.Get("task-1")
+	if err != nil || task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected latest task state after compaction, got %v, %v", task, err)
+	}
+	if history, _ := store.History("task-1"); len(history) != 1 {
+		t.Errorf("Expected history after compaction, got %v", history)
+	}
+}
+
+func TestA2AServer_RecoversFromFileStore(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithTaskStore(store))
+	doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+	})
+	// A task whose handler was still running when the process died
+	_ = store.Put(&models.Task{ID: "test-task-2", Status: models.TaskStatus{State: models.TaskStateWorking}})
+	_ = store.Close()
+
+	store, err = NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	server = NewA2AServer(mockAgentCard, mockTaskHandler, WithTaskStore(store))
+
+	response := doRPC(t, server, "tasks/get", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task-1"}})
+	if response.Error != nil {
+		t.Fatalf("Expected completed task after restart, got %v", response.Error)
+	}
+	var task models.Task
+	decodeResult(t, response.Result, &task)
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCompleted, task.Status.State)
+	}
+
+	response = doRPC(t, server, "tasks/get", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task-2"}})
+	decodeResult(t, response.Result, &task)
+	if task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected interrupted task to be failed, got %s", task.Status.State)
+	}
+}
+
+func TestA2AServer_InvalidPartsKeepStoreReadable(t *testing.T) {
+	metadataOnly := models.Part{Metadata: map[string]interface{}{"note": "no content"}}
+	handlers := map[string]TaskHandler{
+		"returned": func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+			task.Artifacts = append(task.Artifacts, models.Artifact{Parts: []models.Part{metadataOnly}})
+			task.Status.State = models.TaskStateCompleted
+			return task, nil
+		},
+		"streamed": func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+			update(models.TaskArtifactUpdateEvent{ID: task.ID, Artifact: models.Artifact{Parts: []models.Part{metadataOnly}}})
+			task.Status.State = models.TaskStateCompleted
+			return task, nil
+		},
+		"reply": func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+			task.Status = models.TaskStatus{State: models.TaskStateCompleted, Message: &models.Message{Parts: []models.Part{metadataOnly}}}
+			return task, nil
+		},
+	}
+
+	for name, handler := range handlers {
+		dir := t.TempDir()
+		store, err := NewFileTaskStore(dir)
+		if err != nil {
+			t.Fatalf("%s: failed to open store: %v", name, err)
+		}
+		server := NewA2AServer(mockAgentCard, handler, WithTaskStore(store))
+		response := doRPC(t, server, "message/send", sendParams("test-task-1", "hello"))
+		if response.Error == nil || !strings.Contains(response.Error.Message, "invalid") {
+			t.Errorf("%s: expected an invalid part error, got %v", name, response.Error)
+		}
+		_ = store.Close()
+
+		// The agent must still be able to restart
+		store, err = NewFileTaskStore(dir)
+		if err != nil {
+			t.Fatalf("%s: failed to reopen store: %v", name, err)
+		}
+		task, err := store.Get("test-task-1")
+		_ = store.Close()
+		if err != nil {
+			t.Fatalf("%s: failed to load task: %v", name, err)
+		}
+		if task.Status.State != models.TaskStateFailed || len(task.Artifacts) != 0 || task.Status.Message != nil {
+			t.Errorf("%s: expected a failed task without the bad content, got %+v", name, task)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultShutdownTimeout bounds how long a host waits for in-flight work when it stops
+	defaultShutdownTimeout = 30 * time.Second
+	// defaultReadHeaderTimeout bounds how long a client may take to send request headers
+	defaultReadHeaderTimeout = 10 * time.Second
+)
+
+// Middleware wraps the handler of every route a Host serves
+type Middleware func(http.Handler) http.Handler
+
+// HostOption configures a Host
+type HostOption func(*Host)
+
+// WithPublicURL sets the base URL the agents are reached at, such as "https://agents.example.com",
+// when it differs from the listen address (behind a proxy, for example)
+func WithPublicURL(publicURL string) HostOption {
+	return func(h *Host) {
+		h.publicURL = strings.TrimSuffix(publicURL, "/")
+	}
+}
+
+// WithMiddleware adds middleware around every route. The first one given is the outermost.
+func WithMiddleware(middleware ...Middleware) HostOption {
+	return func(h *Host) {
+		h.middleware = append(h.middleware, middleware...)
+	}
+}
+
+// WithShutdownTimeout bounds how long the host waits for streams and queued messages to drain
+// when its context is canceled
+func WithShutdownTimeout(timeout time.Duration) HostOption {
+	return func(h *Host) {
+		if timeout > 0 {
+			h.shutdownTimeout = timeout
+		}
+	}
+}
+
+// WithReadHeaderTimeout bounds how long a client may take to send request headers. Bodies and
+// responses are not bounded, since streams stay open for as long as their task runs.
+func WithReadHeaderTimeout(timeout time.Duration) HostOption {
+	return func(h *Host) {
+		if timeout > 0 {
+			h.readHeaderTimeout = timeout
+		}
+	}
+}
+
+// mountedServer is an A2AServer registered on a Host under a path prefix
+type mountedServer struct {
+	prefix string
+	server *A2AServer
+}
+
+// Host serves several A2AServers from one listener, each under its own path prefix. Besides
+// the agents it serves a registry of their cards at /agents, a liveness check at /healthz and
+// a readiness check at /readyz, which fails once the host starts shutting down. WithAdmin adds
+// a push delivery report at /admin/deliveries.
+type Host struct {
+	addr            string
+	publicURL       string
+	middleware      []Middleware
+	shutdownTimeout time.Duration
+	// readHeaderTimeout guards against clients that open connections and never send a request
+	readHeaderTimeout time.Duration
+	// adminAuth, when set, vets requests to the admin routes, which are only served then
+	adminAuth Authenticator
+
+	servers    []mountedServer
+	ready      atomic.Bool
+	mu         sync.Mutex
+	httpServer *http.Server
+}
+
+// NewHost creates a host that listens on addr, such as ":8080"
+func NewHost(addr string, opts ...HostOption) *Host {
+	h := &Host{
+		addr:              addr,
+		shutdownTimeout:   defaultShutdownTimeout,
+		readHeaderTimeout: defaultReadHeaderTimeout,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Handle registers an agent under prefix, such as "/agent/finance". The agent's card URL is
+// rewritten to point at the prefix when the host starts serving.
+func (h *Host) Handle(prefix string, s *A2AServer) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	h.servers = append(h.servers, mountedServer{prefix: prefix, server: s})
+}
+
+// Handler returns the host's routes wrapped in its middleware
+func (h *Host) Handler() http.Handler {
+	mux := http.NewServeMux()
+	servers := make([]*A2AServer, 0, len(h.servers))
+	for _, mounted := range h.servers {
+		mounted.server.Mount(mux, mounted.prefix)
+		servers = append(servers, mounted.server)
+	}
+	mux.Handle("/agents", NewRegistryHandler(servers...))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !h.ready.Load() {
+			writeHealth(w, http.StatusServiceUnavailable, "unavailable")
+			return
+		}
+		writeHealth(w, http.StatusOK, "ready")
+	})
+	if h.adminAuth != nil {
+		mux.Handle(DeliveryAdminPath, h.deliveryAdminHandler())
+	}
+
+	var handler http.Handler = mux
+	for i := len(h.middleware) - 1; i >= 0; i-- {
+		handler = h.middleware[i](handler)
+	}
+	return handler
+}
+
+// writeHealth answers a health check
+func writeHealth(w http.ResponseWriter, status int, state string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": state}); err != nil {
+		log.Printf("Error encoding health status: %v", err)
+	}
+}
+
+// ListenAndServe listens on the host's address and serves until ctx is canceled, then shuts
+// down gracefully. Cancel ctx on SIGTERM to drain the agents before the process exits.
+func (h *Host) ListenAndServe(ctx context.Context) error {
+	listener, err := net.Listen("tcp", h.addr)
+	if err != nil {
+		return err
+	}
+	return h.Serve(ctx, listener)
+}
+
+// Serve serves on listener until ctx is canceled, then shuts down gracefully, waiting at most
+// the shutdown timeout. Each agent's card URL is derived from the public URL, or else from the
+// listener's address.
+func (h *Host) Serve(ctx context.Context, listener net.Listener) error {
+	baseURL := h.publicURL
+	if baseURL == "" {
+		baseURL = listenURL(listener.Addr())
+	}
+	for _, mounted := range h.servers {
+		mounted.server.agentCard.URL = baseURL + mounted.prefix
+	}
+
+	httpServer := &http.Server{Handler: h.Handler(), ReadHeaderTimeout: h.readHeaderTimeout}
+	h.mu.Lock()
+	h.httpServer = httpServer
+	h.mu.Unlock()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+	h.ready.Store(true)
+
+	select {
+	case err := <-serveErr:
+		h.ready.Store(false)
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), h.shutdownTimeout)
+	defer cancel()
+	err := h.Shutdown(shutdownCtx)
+	if serveErr := <-serveErr; !errors.Is(serveErr, http.ErrServerClosed) {
+		err = errors.Join(err, serveErr)
+	}
+	return err
+}
+
+// Shutdown stops accepting connections and drains every agent: queued messages are processed,
+// running handlers finish and the streams following them end. When ctx expires first, the
+// remaining runs are canceled and open connections are closed.
+func (h *Host) Shutdown(ctx context.Context) error {
+	h.ready.Store(false)
+	h.mu.Lock()
+	httpServer := h.httpServer
+	h.mu.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(h.servers)+1)
+	for i, mounted := range h.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = mounted.server.Shutdown(ctx)
+		}()
+	}
+	if httpServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[len(h.servers)] = httpServer.Shutdown(ctx)
+		}()
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	if err != nil && httpServer != nil {
+		_ = httpServer.Close()
+	}
+
+	// Nothing is served any more, so the agents can release their stores
+	closeErrs := []error{err}
+	for _, mounted := range h.servers {
+		closeErrs = append(closeErrs, mounted.server.Close())
+	}
+	return errors.Join(closeErrs...)
+}
+
+// listenURL turns a listener address into a base URL. Wildcard addresses are reported as
+// localhost, since they cannot be dialed as they are.
+func listenURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + addr.String()
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+// startHost serves host on a loopback port until the test ends, returning its base URL and
+// a channel that receives Serve's result
+func startHost(t *testing.T, ctx context.Context, host *Host) (string, <-chan error) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- host.Serve(ctx, listener) }()
+
+	baseURL := "http://" + listener.Addr().String()
+	waitUntil(t, host.ready.Load)
+	return baseURL, served
+}
+
+func TestHost_Routes(t *testing.T) {
+	other := mockAgentCard
+	other.Name = "Other Agent"
+	tagged := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Host", "test")
+			next.ServeHTTP(w, r)
+		})
+	}
+	host := NewHost("127.0.0.1:0", WithMiddleware(tagged))
+	host.Handle("/agent/one", NewA2AServer(mockAgentCard, mockTaskHandler))
+	host.Handle("agent/two/", NewA2AServer(other, mockTaskHandler))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	baseURL, served := startHost(t, ctx, host)
+
+	resp, err := http.Get(baseURL + "/agents")
+	if err != nil {
+		t.Fatalf("GET /agents failed: %v", err)
+	}
+	var registry AgentRegistry
+	err = json.NewDecoder(resp.Body).Decode(&registry)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to decode registry: %v", err)
+	}
+	if resp.Header.Get("X-Host") != "test" {
+		t.Error("Expected middleware to wrap every route")
+	}
+	if len(registry.Agents) != 2 || registry.Agents[0].URL != baseURL+"/agent/one" || registry.Agents[1].URL != baseURL+"/agent/two" {
+		t.Errorf("Expected card URLs derived from the listener, got %+v", registry.Agents)
+	}
+
+	for _, path := range []string{"/healthz", "/readyz", "/agent/two" + AgentCardPath} {
+		resp, err := http.Get(baseURL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: expected status %d, got %d", path, http.StatusOK, resp.StatusCode)
+		}
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestHost_PublicURL(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	host := NewHost("127.0.0.1:0", WithPublicURL("https://agents.example.com/"))
+	host.Handle("/agent", server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, served := startHost(t, ctx, host)
+	cancel()
+	<-served
+
+	if url := server.AgentCard().URL; url != "https://agents.example.com/agent" {
+		t.Errorf("Expected card URL from the public URL, got %s", url)
+	}
+}
+
+func TestHost_ShutdownDrainsStreams(t *testing.T) {
+	started, release := make(chan string, 1), make(chan struct{})
+	host := NewHost("127.0.0.1:0", WithShutdownTimeout(5*time.Second))
+	host.Handle("/agent", NewA2AServer(mockAgentCard, gatedHandler(started, release)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	baseURL, served := startHost(t, ctx, host)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"message/stream","params":{"id":"test-task-1","message":{"role":"user","parts":[{"text":"hi"}]}}}`
+	resp, err := http.Post(baseURL+"/agent", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	<-started
+
+	cancel()
+	waitUntil(t, func() bool { return !host.ready.Load() })
+	select {
+	case err := <-served:
+		t.Fatalf("Host stopped before the stream ended: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// The stream runs to its final event before the host stops
+	close(release)
+	var finalState models.TaskState
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Result models.TaskStatusUpdateEvent `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		if event.Result.Final != nil && *event.Result.Final {
+			finalState = event.Result.Status.State
+		}
+	}
+	if finalState != models.TaskStateCompleted {
+		t.Errorf("Expected final %s event, got %q", models.TaskStateCompleted, finalState)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestHost_ShutdownClosesStores(t *testing.T) {
+	store, err := NewFileTaskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	host := NewHost("127.0.0.1:0")
+	host.Handle("/agent", NewA2AServer(mockAgentCard, mockTaskHandler, WithTaskStore(store), WithClosers(store)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, served := startHost(t, ctx, host)
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("Expected a clean shutdown, got %v", err)
+	}
+	if err := store.Put(&models.Task{ID: "test-task-1"}); err == nil {
+		t.Error("Expected the store to be closed once the host stopped")
+	}
+}
+
+func TestListenURL(t *testing.T) {
+	tests := map[string]string{
+		"0.0.0.0:8080":   "http://localhost:8080",
+		"[::]:8080":      "http://localhost:8080",
+		"127.0.0.1:9000": "http://127.0.0.1:9000",
+	}
+	for addr, want := range tests {
+		tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+		if err != nil {
+			t.Fatalf("Failed to resolve %s: %v", addr, err)
+		}
+		if got := listenURL(tcpAddr); got != want {
+			t.Errorf("listenURL(%s) = %s, want %s", addr, got, want)
+		}
+	}
+}
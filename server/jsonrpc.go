@@ -0,0 +1,55 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"a2a/models"
+)
+
+// nullID answers requests whose id could not be read
+var nullID = json.RawMessage("null")
+
+// rpcRequest is an incoming JSON-RPC request. The id is kept as raw JSON so that it is echoed
+// back with its original type, and so that a missing id can be told apart from a null one.
+type rpcRequest struct {
+	models.JSONRPCRequest
+	ID json.RawMessage `json:"id"`
+}
+
+// isNotification reports whether the request has no id, meaning the client expects no response
+func (r *rpcRequest) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+// validID reports whether a request id is absent or one of the types JSON-RPC allows: a
+// string, a number or null
+func validID(id json.RawMessage) bool {
+	if len(id) == 0 {
+		return true
+	}
+	decoder := json.NewDecoder(bytes.NewReader(id))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return false
+	}
+	switch value.(type) {
+	case string, json.Number, nil:
+		return true
+	default:
+		return false
+	}
+}
+
+// notificationWriter discards the response to a notification. It still supports flushing so
+// that streaming methods run the same way they do for regular requests.
+type notificationWriter struct {
+	header http.Header
+}
+
+func (n *notificationWriter) Header() http.Header         { return n.header }
+func (n *notificationWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (n *notificationWriter) WriteHeader(int)             {}
+func (n *notificationWriter) Flush()                      {}
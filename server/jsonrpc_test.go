@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"a2a/models"
+)
+
+// postRaw sends a raw JSON-RPC body to the server
+func postRaw(server *A2AServer, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
+func TestA2AServer_RequestIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"string", `"req-1"`},
+		{"int", `42`},
+		{"fraction", `1.50`},
+		{"null", `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewA2AServer(mockAgentCard, mockTaskHandler)
+			w := postRaw(server, `{"jsonrpc":"2.0","id":`+tt.id+`,"method":"tasks/get","params":{"id":"missing"}}`)
+
+			var response struct {
+				JSONRPC string               `json:"jsonrpc"`
+				ID      json.RawMessage      `json:"id"`
+				Error   *models.JSONRPCError `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response %q: %v", w.Body.String(), err)
+			}
+			if string(response.ID) != tt.id {
+				t.Errorf("Expected id %s echoed back, got %s", tt.id, response.ID)
+			}
+			if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotFound) {
+				t.Errorf("Expected task not found error, got %v", response.Error)
+			}
+		})
+	}
+}
+
+func TestA2AServer_StreamNumericID(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	w := postRaw(server, `{"jsonrpc":"2.0","id":7,"method":"message/stream","params":{"id":"test-task-1","message":{"role":"user","parts":[{"text":"Hello"}]}}}`)
+
+	data := streamData(t, w.Body.String())
+	if len(data) == 0 {
+		t.Fatalf("Expected stream events, got %q", w.Body.String())
+	}
+	for _, event := range data {
+		if !strings.Contains(event, `"id":7,`) {
+			t.Errorf("Expected event for request 7, got %s", event)
+		}
+	}
+}
+
+func TestA2AServer_Notification(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	w := postRaw(server, `{"jsonrpc":"2.0","method":"message/send","params":{"id":"test-task-1","message":{"role":"user","parts":[{"text":"Hello"}]}}}`)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected no response body, got %q", w.Body.String())
+	}
+
+	// The message was still processed
+	task, err := server.store.Get("test-task-1")
+	if err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCompleted, task.Status.State)
+	}
+}
+
+func TestA2AServer_InvalidRequestID(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	w := postRaw(server, `{"jsonrpc":"2.0","id":{"nested":true},"method":"tasks/get","params":{"id":"missing"}}`)
+
+	var response struct {
+		ID    json.RawMessage      `json:"id"`
+		Error *models.JSONRPCError `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", w.Body.String(), err)
+	}
+	if string(response.ID) != "null" {
+		t.Errorf("Expected null id, got %s", response.ID)
+	}
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidRequest) {
+		t.Errorf("Expected invalid request error, got %v", response.Error)
+	}
+}
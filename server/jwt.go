@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"a2a/internal/jose"
+)
+
+// defaultJWTLeeway tolerates clock skew between a token's issuer and the server
+const defaultJWTLeeway = 30 * time.Second
+
+// KeySet supplies the keys JWTs are verified with. LoadJWKS opens one.
+type KeySet = jose.KeySet
+
+// LoadJWKS opens a JWKS from a file path, read once, or an http(s) URL, fetched when first
+// needed and again when it goes stale or a token names a key it lacks
+func LoadJWKS(source string) (KeySet, error) {
+	return jose.LoadKeySet(source)
+}
+
+// JWTConfig describes which JWTs a JWTValidator accepts
+type JWTConfig struct {
+	// Keys verifies token signatures
+	Keys KeySet
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+	// Algorithms limits the accepted algorithms (default RS256, ES256 and HS256)
+	Algorithms []string
+	// Leeway tolerates clock skew when checking exp and nbf (default 30s)
+	Leeway time.Duration
+	// SubjectClaim names the claim that identifies the caller (default "sub")
+	SubjectClaim string
+	// Claims maps token claims to the Principal.Claims keys they are copied to
+	Claims map[string]string
+}
+
+// JWTValidator checks bearer tokens that are JWTs signed by one of cfg.Keys. The signature,
+// issuer, audience, expiry and not-before time are all checked; the claims named in cfg.Claims
+// are copied to the principal.
+func JWTValidator(cfg JWTConfig) TokenValidator {
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = jose.Algorithms
+	}
+	leeway := cfg.Leeway
+	if leeway == 0 {
+		leeway = defaultJWTLeeway
+	}
+	subjectClaim := cfg.SubjectClaim
+	if subjectClaim == "" {
+		subjectClaim = "sub"
+	}
+
+	return func(ctx context.Context, token string) (*Principal, error) {
+		parsed, err := jose.Verify(ctx, token, cfg.Keys, algorithms)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidCredentials, err)
+		}
+		var registered jose.Claims
+		if err := parsed.Claims(&registered); err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidCredentials, err)
+		}
+		err = registered.Validate(jose.Expectations{Issuer: cfg.Issuer, Audience: cfg.Audience, Leeway: leeway})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidCredentials, err)
+		}
+
+		var claims map[string]any
+		if err := parsed.Claims(&claims); err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidCredentials, err)
+		}
+		subject, _ := claims[subjectClaim].(string)
+		if subject == "" {
+			return nil, fmt.Errorf("%w: token has no %s claim", errInvalidCredentials, subjectClaim)
+		}
+
+		principal := &Principal{Subject: subject, Claims: make(map[string]any, len(cfg.Claims))}
+		for claim, key := range cfg.Claims {
+			if value, ok := claims[claim]; ok {
+				principal.Claims[key] = value
+			}
+		}
+		return principal, nil
+	}
+}
+
+// ClaimValues returns a claim as a list of strings. A string claim is split on spaces, the way
+// OAuth scopes are written; a list claim keeps its string members.
+func (p *Principal) ClaimValues(key string) []string {
+	switch value := p.Claims[key].(type) {
+	case string:
+		return strings.Fields(value)
+	case []string:
+		return value
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"a2a/internal/jose"
+	"a2a/models"
+)
+
+var testJWTSecret = []byte("test-jwt-secret")
+
+// testJWTKeys holds the secret test tokens are signed with
+var testJWTKeys = jose.StaticKeySet{Set: &jose.JWKS{Keys: []jose.JWK{jose.SecretJWK(testJWTSecret, "test")}}}
+
+// signTestJWT signs claims on top of a valid issuer, audience and expiry
+func signTestJWT(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	now := time.Now()
+	base := map[string]any{"iss": "issuer", "sub": "agent-a", "aud": "finance", "iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+	for claim, value := range claims {
+		if value == nil {
+			delete(base, claim)
+			continue
+		}
+		base[claim] = value
+	}
+	token, err := jose.Sign(jose.Header{Alg: jose.HS256, Kid: "test"}, base, testJWTSecret)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestJWTValidator(t *testing.T) {
+	validate := JWTValidator(JWTConfig{
+		Keys:     testJWTKeys,
+		Issuer:   "issuer",
+		Audience: "finance",
+		Claims:   map[string]string{"scope": "scopes"},
+	})
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+		valid  bool
+	}{
+		{"valid", nil, true},
+		{"audience list", map[string]any{"aud": []string{"compliance", "finance"}}, true},
+		{"wrong issuer", map[string]any{"iss": "someone"}, false},
+		{"wrong audience", map[string]any{"aud": "compliance"}, false},
+		{"expired", map[string]any{"exp": now.Add(-time.Minute).Unix()}, false},
+		{"no expiry", map[string]any{"exp": nil}, false},
+		{"not yet valid", map[string]any{"nbf": now.Add(time.Minute).Unix()}, false},
+		{"skew within leeway", map[string]any{"nbf": now.Add(10 * time.Second).Unix()}, true},
+		{"no subject", map[string]any{"sub": nil}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := validate(context.Background(), signTestJWT(t, tt.claims))
+			if tt.valid && (err != nil || principal.Subject != "agent-a") {
+				t.Errorf("Expected agent-a to be accepted, got %+v (%v)", principal, err)
+			}
+			if !tt.valid && !errors.Is(err, errInvalidCredentials) {
+				t.Errorf("Expected invalid credentials, got %v", err)
+			}
+		})
+	}
+
+	principal, err := validate(context.Background(), signTestJWT(t, map[string]any{"scope": "travel-booking budget-check", "role": "admin"}))
+	if err != nil {
+		t.Fatalf("Expected the token to be accepted, got %v", err)
+	}
+	if !slices.Equal(principal.ClaimValues("scopes"), []string{"travel-booking", "budget-check"}) {
+		t.Errorf("Expected the scope claim to be mapped, got %v", principal.Claims)
+	}
+	if _, ok := principal.Claims["role"]; ok {
+		t.Error("Expected unmapped claims to be left out")
+	}
+
+	forged, _ := jose.Sign(jose.Header{Alg: jose.HS256, Kid: "test"}, map[string]any{"sub": "mallory"}, []byte("guessed"))
+	if _, err := validate(context.Background(), forged); !errors.Is(err, errInvalidCredentials) {
+		t.Errorf("Expected a forged token to be refused, got %v", err)
+	}
+}
+
+func TestA2AServer_JWTCardAuth(t *testing.T) {
+	card := mockAgentCard
+	card.Authentication = &models.AgentAuthentication{Schemes: []string{SchemeBearer}}
+	var principal *Principal
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		principal, _ = PrincipalFromContext(ctx)
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	server := NewA2AServer(card, handler, WithCardAuth(AuthValidators{
+		Bearer: JWTValidator(JWTConfig{Keys: testJWTKeys, Issuer: "issuer", Audience: "finance", Claims: map[string]string{"scope": "scope"}}),
+	}))
+
+	send := func(token string) int {
+		req := newRPCRequest("message/send", sendParams("test-task-1", "Hello"))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send(signTestJWT(t, map[string]any{"aud": "compliance"})); code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for a token meant for another agent, got %d", http.StatusUnauthorized, code)
+	}
+	if code := send(signTestJWT(t, map[string]any{"scope": "budget-check"})); code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+	}
+	if principal == nil || principal.Subject != "agent-a" || principal.Scheme != SchemeBearer {
+		t.Errorf("Expected the handler to see agent-a, got %+v", principal)
+	}
+	if !slices.Contains(principal.ClaimValues("scope"), "budget-check") {
+		t.Errorf("Expected the scope claim on the principal, got %v", principal.Claims)
+	}
+}
+
+func TestPrincipal_ClaimValues(t *testing.T) {
+	principal := &Principal{Claims: map[string]any{
+		"scope":  "a b  c",
+		"roles":  []any{"admin", 7, "user"},
+		"groups": []string{"x"},
+		"level":  3,
+	}}
+	tests := map[string][]string{
+		"scope":   {"a", "b", "c"},
+		"roles":   {"admin", "user"},
+		"groups":  {"x"},
+		"level":   nil,
+		"missing": nil,
+	}
+	for key, want := range tests {
+		if got := principal.ClaimValues(key); !slices.Equal(got, want) {
+			t.Errorf("ClaimValues(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"crypto"
+	"io"
+	"log"
+	"time"
+)
+
+const (
+	// defaultAddr is where Start listens unless WithListenAddress says otherwise
+	defaultAddr = ":8080"
+	// defaultMaxBodySize caps request bodies at 10 MiB
+	defaultMaxBodySize = 10 << 20
+)
+
+// Option configures an A2AServer
+type Option func(*A2AServer)
+
+// WithTaskStore replaces the default in-memory task store
+func WithTaskStore(store TaskStore) Option {
+	return func(s *A2AServer) {
+		s.store = store
+	}
+}
+
+// WithClosers hands the server resources to release in Close once it has shut down, such as
+// stores opened just for it
+func WithClosers(closers ...io.Closer) Option {
+	return func(s *A2AServer) {
+		s.closers = append(s.closers, closers...)
+	}
+}
+
+// WithHandlerTimeout bounds how long a single handler run may take before its context is canceled
+func WithHandlerTimeout(timeout time.Duration) Option {
+	return func(s *A2AServer) {
+		s.handlerTimeout = timeout
+	}
+}
+
+// WithHeartbeatInterval sets how often idle SSE streams get a keepalive comment so that
+// proxies and load balancers do not drop them
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(s *A2AServer) {
+		if interval > 0 {
+			s.heartbeatInterval = interval
+		}
+	}
+}
+
+// WithBatchLimits sets how many requests a JSON-RPC batch may hold and how many of them are
+// run at the same time
+func WithBatchLimits(maxRequests, parallelism int) Option {
+	return func(s *A2AServer) {
+		if maxRequests > 0 {
+			s.maxBatchSize = maxRequests
+		}
+		if parallelism > 0 {
+			s.batchParallelism = parallelism
+		}
+	}
+}
+
+// WithAsyncSend makes message/send return the submitted task immediately and process the
+// message in the background, unless the request sets blocking to true
+func WithAsyncSend(enabled bool) Option {
+	return func(s *A2AServer) {
+		s.asyncSend = enabled
+	}
+}
+
+// WithWorkerPool sets how many background workers process non-blocking sends and how many
+// messages may wait for one before further sends are rejected
+func WithWorkerPool(workers, queueSize int) Option {
+	return func(s *A2AServer) {
+		if workers > 0 {
+			s.workers = workers
+		}
+		if queueSize >= 0 {
+			s.queueSize = queueSize
+		}
+	}
+}
+
+// WithListenAddress sets the address Start listens on (default ":8080")
+func WithListenAddress(addr string) Option {
+	return func(s *A2AServer) {
+		s.addr = addr
+	}
+}
+
+// WithBasePath sets the path Start serves the agent at (default "/")
+func WithBasePath(path string) Option {
+	return func(s *A2AServer) {
+		s.basePath = path
+	}
+}
+
+// WithLogger sets where the server logs errors it cannot report to a caller (default log.Default())
+func WithLogger(logger *log.Logger) Option {
+	return func(s *A2AServer) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithPushTimeout bounds each push notification request
+func WithPushTimeout(timeout time.Duration) Option {
+	return func(s *A2AServer) {
+		if timeout > 0 {
+			s.pushTimeout = timeout
+		}
+	}
+}
+
+// WithPushDelivery sets how push notifications are retried and which addresses they may reach
+func WithPushDelivery(policy DeliveryPolicy) Option {
+	return func(s *A2AServer) {
+		s.deliveryPolicy = policy
+	}
+}
+
+// WithDeadLetterStore keeps the push notifications that could not be delivered in store
+// instead of in memory
+func WithDeadLetterStore(store DeadLetterStore) Option {
+	return func(s *A2AServer) {
+		s.deadLetters = store
+	}
+}
+
+// WithPushSigningKey signs push notifications with key, an RSA or P-256 private key, and
+// publishes its public half at JWKSPath. Without it, agents that send notifications sign them
+// with a key generated at startup.
+func WithPushSigningKey(key crypto.Signer) Option {
+	return func(s *A2AServer) {
+		s.pushSigningKey = key
+	}
+}
+
+// WithMaxBodySize caps the size of a request body in bytes; larger requests are refused with
+// 413 Request Entity Too Large (default 10 MiB)
+func WithMaxBodySize(size int64) Option {
+	return func(s *A2AServer) {
+		if size > 0 {
+			s.maxBodySize = size
+		}
+	}
+}
+
+// WithCORS lets browsers on the policy's origins call the agent
+func WithCORS(policy CORSPolicy) Option {
+	return func(s *A2AServer) {
+		s.cors = &policy
+	}
+}
+
+// WithAuthenticator makes every JSON-RPC request pass authenticate first. The agent card
+// stays public.
+func WithAuthenticator(authenticate Authenticator) Option {
+	return func(s *A2AServer) {
+		s.authenticate = authenticate
+	}
+}
+
+// WithCardAuth enforces the authentication schemes declared on the agent card, checking
+// credentials with validators. Handlers find the caller with PrincipalFromContext.
+func WithCardAuth(validators AuthValidators) Option {
+	return func(s *A2AServer) {
+		s.authenticate = s.cardAuthenticator(validators)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"a2a/models"
+)
+
+func TestA2AServer_MaxBodySize(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithMaxBodySize(64))
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"id":"test-task-1","message":{"role":"user","parts":[{"text":"` +
+		strings.Repeat("x", 100) + `"}]}}}`
+	w := postRaw(server, body)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+	var response rpcResult
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidRequest) || string(response.Error.Data) != `{"limit":64}` {
+		t.Errorf("Expected an invalid request error with the limit, got %+v", response.Error)
+	}
+}
+
+func TestA2AServer_WithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithLogger(log.New(&buf, "test: ", 0)))
+
+	server.writeJSON(&failingWriter{header: http.Header{}}, http.StatusOK, "response")
+	if !strings.HasPrefix(buf.String(), "test: Error encoding response") {
+		t.Errorf("Expected the error on the configured logger, got %q", buf.String())
+	}
+}
+
+// failingWriter is a ResponseWriter whose writes always fail
+type failingWriter struct {
+	header http.Header
+}
+
+func (w *failingWriter) Header() http.Header { return w.header }
+func (w *failingWriter) Write([]byte) (int, error) {
+	return 0, http.ErrHandlerTimeout
+}
+func (w *failingWriter) WriteHeader(int) {}
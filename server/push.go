@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"a2a/models"
+)
+
+// pushNotificationTimeout bounds a single outbound notification request
+const pushNotificationTimeout = 10 * time.Second
+
+// setupPushDelivery prepares the client notifications are sent with, following the delivery
+// policy, and the store for the ones that cannot be delivered
+func (s *A2AServer) setupPushDelivery() {
+	s.deliveryPolicy = s.deliveryPolicy.withDefaults()
+	s.pushClient = newPushClient(s.pushTimeout, s.deliveryPolicy)
+	if s.deadLetters == nil {
+		s.deadLetters = NewMemoryDeadLetterStore()
+	}
+}
+
+// supportsPushNotifications reports whether the agent card advertises push notifications
+func (s *A2AServer) supportsPushNotifications() bool {
+	return s.agentCard.Capabilities.PushNotifications != nil && *s.agentCard.Capabilities.PushNotifications
+}
+
+// setPushConfig stores the push notification configuration for a task. Finished tasks send no
+// more events, so nothing is kept for them.
+func (s *A2AServer) setPushConfig(taskID string, config models.PushNotificationConfig) {
+	if task, err := s.store.Get(taskID); err == nil && task.Status.State.IsTerminal() {
+		return
+	}
+	s.pushMu.Lock()
+	defer s.pushMu.Unlock()
+	s.pushConfigs[taskID] = &config
+}
+
+// deletePushConfig forgets the push notification configuration for a task
+func (s *A2AServer) deletePushConfig(taskID string) {
+	s.pushMu.Lock()
+	defer s.pushMu.Unlock()
+	delete(s.pushConfigs, taskID)
+}
+
+// getPushConfig returns the push notification configuration for a task, if any
+func (s *A2AServer) getPushConfig(taskID string) (*models.PushNotificationConfig, bool) {
+	s.pushMu.RLock()
+	defer s.pushMu.RUnlock()
+	config, exists := s.pushConfigs[taskID]
+	return config, exists
+}
+
+// sendPushNotification queues a task event for delivery to the URL configured for the task.
+// Delivery happens in the background so a slow receiver never blocks the handler; a task's
+// events reach the receiver in order, each signed so the receiver can check it came from this
+// agent.
+func (s *A2AServer) sendPushNotification(taskID string, event any) {
+	config, exists := s.getPushConfig(taskID)
+	if !exists {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Printf("Error encoding push notification for task %s: %v", taskID, err)
+		return
+	}
+	s.enqueueNotification(&pushDelivery{taskID: taskID, config: *config, body: body})
+
+	// The final status is the last event of a finished task; the queued delivery keeps its own
+	// copy of the config
+	if status, ok := event.(models.TaskStatusUpdateEvent); ok && status.Status.State.IsTerminal() {
+		s.deletePushConfig(taskID)
+	}
+}
+
+// setPushAuthHeaders attaches the verification token and any receiver credentials to a notification request
+func setPushAuthHeaders(req *http.Request, config *models.PushNotificationConfig) {
+	if config.Token != nil {
+		req.Header.Set("X-A2A-Notification-Token", *config.Token)
+	}
+
+	auth := config.Authentication
+	if auth == nil || auth.Credentials == nil {
+		return
+	}
+	for _, scheme := range auth.Schemes {
+		if strings.EqualFold(scheme, "bearer") {
+			req.Header.Set("Authorization", "Bearer "+*auth.Credentials)
+			return
+		}
+	}
+}
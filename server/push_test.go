@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"a2a/internal/jose"
+	"a2a/models"
+)
+
+// allowLoopback lets notifications reach the httptest receivers, which listen on loopback
+var allowLoopback = WithPushDelivery(DeliveryPolicy{AllowPrivateAddresses: true})
+
+// pushAgentCard is mockAgentCard with push notifications enabled
+func pushAgentCard() models.AgentCard {
+	card := mockAgentCard
+	card.Capabilities.PushNotifications = boolPtr(true)
+	return card
+}
+
+func TestA2AServer_PushNotificationSetGet(t *testing.T) {
+	server := NewA2AServer(pushAgentCard(), mockInputTaskHandler)
+
+	config := models.TaskPushNotificationConfig{
+		ID: "test-task-1",
+		PushNotificationConfig: models.PushNotificationConfig{
+			URL:   "http://example.com/hook",
+			Token: stringPtr("secret"),
+		},
+	}
+
+	// Setting a config for an unknown task fails
+	response := doRPC(t, server, "tasks/pushNotification/set", config)
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotFound) {
+		t.Fatalf("Expected task not found error, got %v", response.Error)
+	}
+
+	doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+	})
+
+	response = doRPC(t, server, "tasks/pushNotification/set", config)
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	response = doRPC(t, server, "tasks/pushNotification/get", models.TaskIDParams{ID: "test-task-1"})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	var got models.TaskPushNotificationConfig
+	decodeResult(t, response.Result, &got)
+	if got.ID != "test-task-1" {
+		t.Errorf("Expected task ID %s, got %s", "test-task-1", got.ID)
+	}
+	if got.PushNotificationConfig.URL != "http://example.com/hook" {
+		t.Errorf("Expected URL %s, got %s", "http://example.com/hook", got.PushNotificationConfig.URL)
+	}
+	if got.PushNotificationConfig.Token == nil || *got.PushNotificationConfig.Token != "secret" {
+		t.Errorf("Expected token to round-trip, got %v", got.PushNotificationConfig.Token)
+	}
+}
+
+func TestA2AServer_PushNotificationNotSupported(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+
+	response := doRPC(t, server, "tasks/pushNotification/get", models.TaskIDParams{ID: "test-task-1"})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodePushNotificationNotSupported) {
+		t.Fatalf("Expected push notification not supported error, got %v", response.Error)
+	}
+}
+
+func TestA2AServer_PushNotificationDelivery(t *testing.T) {
+	type received struct {
+		token string
+		event map[string]any
+	}
+	events := make(chan received, 10)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode notification: %v", err)
+		}
+		events <- received{token: r.Header.Get("X-A2A-Notification-Token"), event: event}
+	}))
+	defer receiver.Close()
+
+	server := NewA2AServer(pushAgentCard(), mockTaskHandler, allowLoopback)
+
+	response := doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+		PushNotification: &models.PushNotificationConfig{
+			URL:   receiver.URL,
+			Token: stringPtr("secret"),
+		},
+	})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	// Notifications are delivered asynchronously, so wait for the final one
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case got := <-events:
+			if got.token != "secret" {
+				t.Errorf("Expected token %s, got %s", "secret", got.token)
+			}
+			if got.event["id"] != "test-task-1" {
+				t.Errorf("Expected task ID %s, got %v", "test-task-1", got.event["id"])
+			}
+			if got.event["final"] == true {
+				status, _ := got.event["status"].(map[string]any)
+				if status["state"] != string(models.TaskStateCompleted) {
+					t.Errorf("Expected final state %s, got %v", models.TaskStateCompleted, status["state"])
+				}
+
+				// A finished task sends nothing more, so its config is dropped and cannot be set again
+				if _, exists := server.getPushConfig("test-task-1"); exists {
+					t.Error("Expected the push config to be dropped once the task finished")
+				}
+				response := doRPC(t, server, "tasks/pushNotification/set", models.TaskPushNotificationConfig{
+					ID:                     "test-task-1",
+					PushNotificationConfig: models.PushNotificationConfig{URL: receiver.URL},
+				})
+				if response.Error == nil || response.Error.Code != int(models.ErrorCodeInvalidParams) {
+					t.Errorf("Expected setting a config on a finished task to fail, got %v", response.Error)
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("Timed out waiting for final push notification")
+		}
+	}
+}
+
+func TestA2AServer_SignedPushNotification(t *testing.T) {
+	signatures := make(chan string, 10)
+	bodies := make(chan []byte, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signatures <- r.Header.Get(NotificationSignatureHeader)
+		bodies <- body
+	}))
+	defer receiver.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	server := NewA2AServer(pushAgentCard(), mockTaskHandler, WithPushSigningKey(key), allowLoopback)
+
+	// The JWKS publishes the public half of the configured key
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/agent"+JWKSPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	set, err := jose.ParseJWKS(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse JWKS: %v", err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].Kty != jose.KeyTypeRSA || set.Keys[0].Kid == "" {
+		t.Fatalf("Expected one named RSA key, got %+v", set.Keys)
+	}
+
+	doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:               "test-task-1",
+		Message:          models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+		PushNotification: &models.PushNotificationConfig{URL: receiver.URL},
+	})
+
+	var signature string
+	var body []byte
+	select {
+	case signature = <-signatures:
+		body = <-bodies
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a push notification")
+	}
+	token, err := jose.Verify(context.Background(), signature, jose.StaticKeySet{Set: set}, []string{jose.RS256})
+	if err != nil {
+		t.Fatalf("Expected the signature to verify against the JWKS, got %v", err)
+	}
+	var claims NotificationClaims
+	if err := token.Claims(&claims); err != nil {
+		t.Fatalf("Failed to decode claims: %v", err)
+	}
+	digest := sha256.Sum256(body)
+	if claims.BodySHA256 != hex.EncodeToString(digest[:]) || claims.TaskID != "test-task-1" {
+		t.Errorf("Expected the claims to bind the body and task, got %+v", claims)
+	}
+	if claims.ID == "" || claims.IssuedAt == 0 || claims.Audience[0] != receiver.URL {
+		t.Errorf("Expected jti, iat and the receiver as audience, got %+v", claims)
+	}
+
+	// Agents without a configured key sign with a generated one; agents without push publish none
+	generated := NewA2AServer(pushAgentCard(), mockTaskHandler)
+	if generated.pushSigner == nil || generated.pushSigner.alg != jose.ES256 {
+		t.Errorf("Expected a generated ES256 key, got %+v", generated.pushSigner)
+	}
+	w = httptest.NewRecorder()
+	NewA2AServer(mockAgentCard, mockTaskHandler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, JWKSPath, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"keys":[]`) {
+		t.Errorf("Expected an empty JWKS, got %d %s", w.Code, w.Body.String())
+	}
+}
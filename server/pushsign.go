@@ -0,0 +1,130 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"a2a/internal/jose"
+	"a2a/models"
+)
+
+// JWKSPath is where an agent publishes the keys its push notifications are signed with,
+// relative to the agent's endpoint
+const JWKSPath = "/.well-known/jwks.json"
+
+// NotificationSignatureHeader carries the JWT that signs a push notification. Receivers verify
+// it against the agent's JWKS; the client package has a helper for that.
+const NotificationSignatureHeader = "X-A2A-Notification-Signature"
+
+// notificationLifetime is how long a notification signature stays valid
+const notificationLifetime = 5 * time.Minute
+
+// NotificationClaims are the claims of a push notification signature
+type NotificationClaims struct {
+	jose.Claims
+	// TaskID is the task the notification reports on
+	TaskID string `json:"taskId"`
+	// BodySHA256 is the hex SHA-256 digest of the request body, binding the signature to it
+	BodySHA256 string `json:"request_body_sha256"`
+}
+
+// pushSigner signs outbound push notifications
+type pushSigner struct {
+	key crypto.Signer
+	alg string
+	jwk jose.JWK
+}
+
+// newPushSigner prepares key for signing, naming it by its thumbprint
+func newPushSigner(key crypto.Signer) (*pushSigner, error) {
+	alg, err := jose.SigningAlgorithm(key)
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := jose.PublicJWK(key.Public(), "")
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Kid, err = jose.Thumbprint(jwk); err != nil {
+		return nil, err
+	}
+	return &pushSigner{key: key, alg: alg, jwk: jwk}, nil
+}
+
+// setupPushSigner picks the key notifications are signed with: the one given with
+// WithPushSigningKey or, for agents that send notifications, a key generated for this process.
+// Receivers fetch the JWKS again when a restart brings a new key.
+func (s *A2AServer) setupPushSigner() {
+	if s.pushSigningKey != nil {
+		signer, err := newPushSigner(s.pushSigningKey)
+		if err == nil {
+			s.pushSigner = signer
+			return
+		}
+		s.logger.Printf("Cannot sign push notifications with the configured key, using a generated one: %v", err)
+	}
+	if !s.supportsPushNotifications() {
+		return
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		s.logger.Printf("Error generating push notification signing key: %v", err)
+		return
+	}
+	if s.pushSigner, err = newPushSigner(key); err != nil {
+		s.logger.Printf("Error preparing push notification signing key: %v", err)
+	}
+}
+
+// signNotification signs a notification about taskID with body for the receiver at url
+func (s *A2AServer) signNotification(url, taskID string, body []byte) (string, error) {
+	now := time.Now()
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(body)
+	claims := NotificationClaims{
+		Claims: jose.Claims{
+			Issuer:    s.agentCard.URL,
+			Audience:  jose.Audience{url},
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(notificationLifetime).Unix(),
+			ID:        hex.EncodeToString(jti),
+		},
+		TaskID:     taskID,
+		BodySHA256: hex.EncodeToString(digest[:]),
+	}
+	return jose.Sign(jose.Header{Alg: s.pushSigner.alg, Kid: s.pushSigner.jwk.Kid}, claims, s.pushSigner.key)
+}
+
+// isJWKSPath reports whether r asks for the agent's signing keys
+func isJWKSPath(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, JWKSPath)
+}
+
+// serveJWKS publishes the public key push notifications are signed with
+func (s *A2AServer) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.writeJSON(w, http.StatusMethodNotAllowed, errorResponse(nullID, NewError(models.ErrorCodeInvalidRequest, "Method not allowed")))
+		return
+	}
+	set := jose.JWKS{Keys: []jose.JWK{}}
+	if s.pushSigner != nil {
+		set.Keys = append(set.Keys, s.pushSigner.jwk)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "max-age=300")
+	if err := json.NewEncoder(w).Encode(set); err != nil {
+		s.logger.Printf("Error encoding JWKS: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"a2a/models"
+)
+
+// errBlockedAddress is reported when a push notification would reach a private, loopback or
+// link-local address and the delivery policy does not allow it
+var errBlockedAddress = errors.New("push notification address is not allowed")
+
+// blockedIP reports whether ip lies in a range push notifications may not reach unless the
+// delivery policy allows private addresses
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// checkPushURL vets the URL of a push notification config; field names it in the error. Names are only checked here when
+// they obviously point at this machine; the addresses they resolve to are checked again when
+// the notification is sent, so a name cannot be re-pointed at an internal service later.
+func (s *A2AServer) checkPushURL(rawURL, field string) *Error {
+	invalid := func(reason string) *Error {
+		return NewError(models.ErrorCodeInvalidParams, "Push notification URL is not allowed").
+			WithData(map[string]string{"field": field, "reason": reason})
+	}
+	if rawURL == "" {
+		return NewError(models.ErrorCodeInvalidParams, "Push notification URL is required").WithData(map[string]any{"field": field})
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return invalid("not an absolute http(s) URL")
+	}
+	if s.deliveryPolicy.AllowPrivateAddresses {
+		return nil
+	}
+	host := strings.ToLower(target.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return invalid("loopback address")
+	}
+	if ip := net.ParseIP(host); ip != nil && blockedIP(ip) {
+		return invalid("private or loopback address")
+	}
+	return nil
+}
+
+// newPushClient builds the HTTP client notifications are sent with. Unless the policy allows
+// private addresses, every connection is checked after name resolution, and proxies are not
+// used since they would hide the receiver's address from the check. Redirects are not followed.
+func newPushClient(timeout time.Duration, policy DeliveryPolicy) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !policy.AllowPrivateAddresses {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+					return errBlockedAddress
+				}
+				return nil
+			},
+		}
+		transport.Proxy = nil
+		transport.DialContext = dialer.DialContext
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
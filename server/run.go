@@ -0,0 +1,224 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+
+	"a2a/models"
+)
+
+// errTaskCanceled is recorded as the cancellation cause when a client calls tasks/cancel
+var errTaskCanceled = errors.New("task canceled by client")
+
+// taskRun tracks a handler invocation that is in flight so it can be canceled
+type taskRun struct {
+	cancel context.CancelCauseFunc
+}
+
+// startRun derives the context for a handler run from parent, applying the configured
+// handler timeout, and registers it so tasks/cancel can reach it. The returned function
+// must be called once the run is over.
+func (s *A2AServer) startRun(parent context.Context, taskID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	stop := func() { cancel(nil) }
+	if s.handlerTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, s.handlerTimeout)
+		stop = func() {
+			cancelTimeout()
+			cancel(nil)
+		}
+	}
+
+	// A shutdown that runs out of time cancels every run, including ones that start afterwards
+	stopAfterShutdown := context.AfterFunc(s.stopCtx, func() { cancel(context.Cause(s.stopCtx)) })
+
+	run := &taskRun{cancel: cancel}
+	s.runsMu.Lock()
+	s.runs[taskID] = run
+	s.runsMu.Unlock()
+
+	return ctx, func() {
+		s.runsMu.Lock()
+		if s.runs[taskID] == run {
+			delete(s.runs, taskID)
+		}
+		if len(s.runs) == 0 && s.runsIdle != nil {
+			close(s.runsIdle)
+			s.runsIdle = nil
+		}
+		s.runsMu.Unlock()
+		stopAfterShutdown()
+		stop()
+	}
+}
+
+// cancelRun cancels the handler currently running for a task, reporting whether there was one
+func (s *A2AServer) cancelRun(taskID string) bool {
+	s.runsMu.Lock()
+	run, exists := s.runs[taskID]
+	s.runsMu.Unlock()
+	if !exists {
+		return false
+	}
+	run.cancel(errTaskCanceled)
+	return true
+}
+
+// isRunning reports whether a handler is currently running for the task
+func (s *A2AServer) isRunning(taskID string) bool {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+	_, exists := s.runs[taskID]
+	return exists
+}
+
+// beginTask marks the task as working, records the incoming message and registers the run
+// with startRun. A task that already exists is continued, keeping its artifacts and metadata.
+// The run is registered before s.mu is released, so a non-blocking send that arrives meanwhile
+// sees the task as running rather than trying to move it from working back to submitted.
+func (s *A2AServer) beginTask(ctx context.Context, params *models.TaskSendParams) (*models.Task, context.Context, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, err := s.store.Get(params.ID)
+	if errors.Is(err, ErrTaskNotFound) {
+		task = &models.Task{ID: params.ID}
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Finished tasks are not reopened; the client has to start a new task
+	if err := checkTransition(task.ID, task.Status.State, models.TaskStateWorking); err != nil {
+		return nil, nil, nil, err
+	}
+
+	s.setStatus(task, models.TaskStatus{State: models.TaskStateWorking})
+	if err := s.store.Put(task); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.store.AppendHistory(task.ID, &params.Message); err != nil {
+		return nil, nil, nil, err
+	}
+
+	ctx, done := s.startRun(ctx, task.ID)
+	return task, ctx, done, nil
+}
+
+// runTask invokes the handler for one message and stores the outcome, publishing the initial,
+// intermediate and final events to log. It returns the stored task, and the handler's error
+// if the run failed.
+func (s *A2AServer) runTask(ctx context.Context, log *eventLog, task *models.Task, message *models.Message) (*models.Task, error) {
+	// An update the store could not read back is left out, and fails the run once the
+	// handler returns
+	var invalidUpdate error
+	updateFunc := func(event any) {
+		if err := checkUpdate(event); err != nil {
+			s.logger.Printf("Task %s: dropping update: %v", task.ID, err)
+			invalidUpdate = cmp.Or(invalidUpdate, err)
+			return
+		}
+		recordArtifact(task, event)
+		s.recordStatus(task, event)
+		s.publish(log, task.ID, event)
+	}
+
+	// Send initial status update
+	updateFunc(models.TaskStatusUpdateEvent{
+		ID:     task.ID,
+		Status: task.Status,
+		Final:  boolPtr(false),
+	})
+
+	updatedTask, err := s.callHandler(ctx, task, message, updateFunc)
+	switch {
+	case errors.Is(context.Cause(ctx), errTaskCanceled):
+		// tasks/cancel takes precedence over whatever the handler came back with
+		updatedTask, err = task, nil
+		updatedTask.Status = models.TaskStatus{State: models.TaskStateCanceled}
+	case errors.Is(context.Cause(ctx), errServerShutdown):
+		// The server stopped before the handler finished; the task cannot be picked up again
+		updatedTask, err = task, errServerShutdown
+		updatedTask.Status = models.TaskStatus{State: models.TaskStateFailed}
+	case err != nil && errors.Is(ctx.Err(), context.Canceled):
+		// The handler gave up because the client went away
+		updatedTask = task
+		updatedTask.Status = models.TaskStatus{State: models.TaskStateCanceled}
+	case err != nil:
+		updatedTask = task
+		updatedTask.Status = models.TaskStatus{State: models.TaskStateFailed}
+	case invalidUpdate != nil:
+		updatedTask, err = task, invalidUpdate
+		updatedTask.Status = models.TaskStatus{State: models.TaskStateFailed}
+	case updatedTask == nil:
+		updatedTask, err = task, errors.New("handler returned no task")
+		updatedTask.Status = models.TaskStatus{State: models.TaskStateFailed}
+	}
+
+	if invalid := prepareForStore(task, updatedTask); invalid != nil && err == nil {
+		err = invalid
+		updatedTask.Status = models.TaskStatus{State: models.TaskStateFailed}
+	}
+	s.mu.Lock()
+	updatedTask, err, storeErr := s.settleTask(updatedTask, err)
+	s.mu.Unlock()
+
+	// Tell stream subscribers why the run failed before the final status ends the stream
+	if err != nil && updatedTask.Status.State != models.TaskStateCanceled {
+		s.publishError(log, err)
+	} else if storeErr != nil {
+		s.publishError(log, fmt.Errorf("store task: %w", storeErr))
+	}
+
+	// Send final status update
+	s.publish(log, updatedTask.ID, models.TaskStatusUpdateEvent{
+		ID:     updatedTask.ID,
+		Status: updatedTask.Status,
+		Final:  boolPtr(true),
+	})
+
+	if err != nil {
+		return updatedTask, err
+	}
+	if storeErr != nil {
+		return updatedTask, fmt.Errorf("store task: %w", storeErr)
+	}
+	return updatedTask, nil
+}
+
+// callHandler invokes the handler, turning a panic into an error so the task fails instead
+// of being left working with nobody to finish it
+func (s *A2AServer) callHandler(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (updatedTask *models.Task, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			updatedTask, err = nil, fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return s.handler(ctx, task, message, update)
+}
+
+// settleTask stores the outcome of a run, checking it against the state machine. A task that
+// was canceled while the handler finished keeps its canceled state; a handler that reports an
+// illegal state fails the task. It returns the stored task, the run error and any store error.
+// Must be called with s.mu held.
+func (s *A2AServer) settleTask(task *models.Task, runErr error) (*models.Task, error, error) {
+	current, err := s.store.Get(task.ID)
+	if err != nil && !errors.Is(err, ErrTaskNotFound) {
+		return task, runErr, err
+	}
+	if err == nil && !current.Status.State.CanTransitionTo(task.Status.State) {
+		if current.Status.State.IsTerminal() {
+			return current, runErr, nil
+		}
+		runErr = fmt.Errorf("handler returned illegal state: %v", checkTransition(task.ID, current.Status.State, task.Status.State))
+		task.Status = models.TaskStatus{State: models.TaskStateFailed}
+	}
+
+	s.setStatus(task, task.Status)
+	if err := s.store.Put(task); err != nil {
+		return task, runErr, err
+	}
+	return task, runErr, s.recordAgentReply(task)
+}
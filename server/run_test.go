@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+// blockingHandler waits until its context is done, reporting on started and stopped
+func blockingHandler(started, stopped chan<- struct{}) TaskHandler {
+	return func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+		return nil, ctx.Err()
+	}
+}
+
+// sendParams builds message/send parameters carrying a single text part
+func sendParams(taskID, text string) models.TaskSendParams {
+	return models.TaskSendParams{
+		ID:      taskID,
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr(text)}}},
+	}
+}
+
+// waitFor fails the test if ch is not closed in time
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for %s", what)
+	}
+}
+
+func TestA2AServer_CancelStopsRunningHandler(t *testing.T) {
+	started, stopped := make(chan struct{}), make(chan struct{})
+	server := NewA2AServer(mockAgentCard, blockingHandler(started, stopped))
+
+	streamDone := make(chan struct{})
+	w := httptest.NewRecorder()
+	go func() {
+		server.ServeHTTP(w, newRPCRequest("message/stream", sendParams("test-task-1", "Hello")))
+		close(streamDone)
+	}()
+	waitFor(t, started, "handler to start")
+
+	response := doRPC(t, server, "tasks/cancel", models.TaskIDParams{ID: "test-task-1"})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	waitFor(t, stopped, "handler to observe cancellation")
+	waitFor(t, streamDone, "stream to finish")
+
+	events := decodeStreamEvents(t, w.Body.String())
+	status, _ := events[len(events)-1]["status"].(map[string]any)
+	if status["state"] != string(models.TaskStateCanceled) {
+		t.Errorf("Expected final streamed state %s, got %v", models.TaskStateCanceled, status["state"])
+	}
+
+	response = doRPC(t, server, "tasks/get", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task-1"}})
+	var task models.Task
+	decodeResult(t, response.Result, &task)
+	if task.Status.State != models.TaskStateCanceled {
+		t.Errorf("Expected stored state %s, got %s", models.TaskStateCanceled, task.Status.State)
+	}
+}
+
+func TestA2AServer_ClientDisconnectStopsHandler(t *testing.T) {
+	started, stopped := make(chan struct{}), make(chan struct{})
+	server := NewA2AServer(mockAgentCard, blockingHandler(started, stopped))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := newRPCRequest("message/send", sendParams("test-task-1", "Hello")).WithContext(ctx)
+	go server.ServeHTTP(httptest.NewRecorder(), req)
+
+	waitFor(t, started, "handler to start")
+	cancel()
+	waitFor(t, stopped, "handler to observe the disconnect")
+}
+
+func TestA2AServer_HandlerTimeout(t *testing.T) {
+	started, stopped := make(chan struct{}), make(chan struct{})
+	server := NewA2AServer(mockAgentCard, blockingHandler(started, stopped), WithHandlerTimeout(20*time.Millisecond))
+
+	response := doRPC(t, server, "message/send", sendParams("test-task-1", "Hello"))
+	if response.Error == nil {
+		t.Fatal("Expected an error once the handler deadline passed")
+	}
+
+	task, err := server.store.Get("test-task-1")
+	if err != nil {
+		t.Fatalf("Expected task to be stored, got %v", err)
+	}
+	if task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected state %s after timeout, got %s", models.TaskStateFailed, task.Status.State)
+	}
+}
+
+func TestAdaptLegacyHandler(t *testing.T) {
+	legacy := func(task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		if *message.Parts[0].Text != "Hello" {
+			return nil, errors.New("unexpected message")
+		}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, AdaptLegacyHandler(legacy))
+
+	response := doRPC(t, server, "message/send", sendParams("test-task-1", "Hello"))
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	var task models.Task
+	decodeResult(t, response.Result, &task)
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCompleted, task.Status.State)
+	}
+}
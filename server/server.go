@@ -1,215 +1,549 @@
 package server
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"io"
+	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"a2a/models"
 )
 
 // TaskHandler is a function type that handles task processing
-// Added update func(any) to support streaming updates from within the handler
-type TaskHandler func(task *models.Task, message *models.Message, update func(any)) (*models.Task, error)
+// update streams intermediate events to subscribers. ctx is canceled when the task is
+// canceled with tasks/cancel, when a message/send client disconnects, or when the
+// handler timeout expires; long-running handlers should return promptly once it is done.
+type TaskHandler func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error)
+
+// LegacyTaskHandler is the handler signature used before handlers received a context
+type LegacyTaskHandler func(task *models.Task, message *models.Message, update func(any)) (*models.Task, error)
+
+// AdaptLegacyHandler lets a handler written against the old signature be used with NewA2AServer.
+// The wrapped handler cannot observe cancellation, so it always runs to completion; the task
+// still ends up canceled if tasks/cancel was called in the meantime.
+func AdaptLegacyHandler(handler LegacyTaskHandler) TaskHandler {
+	return func(_ context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		return handler(task, message, update)
+	}
+}
 
 // A2AServer represents an A2A server instance
 type A2AServer struct {
-	agentCard   models.AgentCard
-	handler     TaskHandler
-	port        int
-	basePath    string
-	taskStore   map[string]*models.Task
-	taskHistory map[string][]*models.Message
-	mu          sync.RWMutex
+	agentCard models.AgentCard
+	handler   TaskHandler
+	// addr and basePath are where Start serves the agent
+	addr     string
+	basePath string
+	store    TaskStore
+	logger   *log.Logger
+	// mu guards read-modify-write updates of stored tasks; it is never held while a handler runs
+	mu          sync.Mutex
+	taskLocks   *taskLocks
+	pushConfigs map[string]*models.PushNotificationConfig
+	pushClient  *http.Client
+	pushTimeout time.Duration
+	pushMu      sync.RWMutex
+	// pushSigningKey is the key given with WithPushSigningKey; pushSigner signs with it
+	pushSigningKey crypto.Signer
+	pushSigner     *pushSigner
+	// deliveryQueues holds each task's undelivered notifications; guarded by deliveryMu
+	deliveryPolicy DeliveryPolicy
+	deadLetters    DeadLetterStore
+	deliveryQueues map[string]*deliveryQueue
+	deliveryMu     sync.Mutex
+	deliveryStats  deliveryCounters
+	// deliveriesIdle is closed when the last queue drains while Shutdown waits for it
+	deliveriesIdle chan struct{}
+	eventLogs      map[string]*eventLog
+	eventsMu       sync.Mutex
+	runs           map[string]*taskRun
+	runsMu         sync.Mutex
+	// handlerTimeout bounds each handler run; zero means no limit
+	handlerTimeout time.Duration
+	// heartbeatInterval is how often idle SSE streams get a keepalive comment
+	heartbeatInterval time.Duration
+	// eventLogRetention is how long a finished run's event log is kept for resubscribing clients
+	eventLogRetention time.Duration
+	// maxBodySize caps the size of a request body in bytes
+	maxBodySize int64
+	// cors, when set, lets browsers on the allowed origins call the agent
+	cors *CORSPolicy
+	// authenticate, when set, vets every JSON-RPC request before it is read
+	authenticate Authenticator
+	// maxBatchSize and batchParallelism limit JSON-RPC batches
+	maxBatchSize     int
+	batchParallelism int
+	// asyncSend makes message/send return immediately unless the caller asks to block
+	asyncSend bool
+	workers   int
+	queueSize int
+	// queue holds the messages waiting for a worker, oldest first; guarded by s.mu, and
+	// queueChanged is signaled whenever a worker might find something new to pick
+	queue        []sendJob
+	queueChanged *sync.Cond
+	startWorkers sync.Once
+	workerGroup  sync.WaitGroup
+	// closing is set by Shutdown; a closing server takes no new messages
+	closing atomic.Bool
+	// closers are released by Close, after Shutdown
+	closers []io.Closer
+	// stopCtx is canceled when Shutdown gives up waiting, which cancels every run
+	stopCtx  context.Context
+	stopRuns context.CancelCauseFunc
+	// runsIdle is closed when the last run ends while Shutdown waits for it; guarded by runsMu
+	runsIdle chan struct{}
 }
 
 // NewA2AServer creates a new A2A server instance
-// Updated signature to match the new TaskHandler
-func NewA2AServer(agentCard models.AgentCard, handler TaskHandler) *A2AServer {
-	return &A2AServer{
-		agentCard:   agentCard,
-		handler:     handler,
-		taskStore:   make(map[string]*models.Task),
-		taskHistory: make(map[string][]*models.Message),
+// Tasks are kept in memory unless a different store is supplied with WithTaskStore
+func NewA2AServer(agentCard models.AgentCard, handler TaskHandler, opts ...Option) *A2AServer {
+	s := &A2AServer{
+		agentCard:      agentCard,
+		handler:        handler,
+		addr:           defaultAddr,
+		basePath:       "/",
+		store:          NewMemoryTaskStore(),
+		logger:         log.Default(),
+		pushConfigs:    make(map[string]*models.PushNotificationConfig),
+		deliveryQueues: make(map[string]*deliveryQueue),
+		pushTimeout:    pushNotificationTimeout,
+		eventLogs:      make(map[string]*eventLog),
+		runs:           make(map[string]*taskRun),
+		taskLocks:      newTaskLocks(),
+		workers:        defaultWorkers,
+		queueSize:      defaultQueueSize,
+
+		maxBodySize:       defaultMaxBodySize,
+		heartbeatInterval: defaultHeartbeatInterval,
+		eventLogRetention: defaultEventLogRetention,
+		maxBatchSize:      defaultMaxBatchSize,
+		batchParallelism:  defaultBatchParallelism,
+	}
+	s.queueChanged = sync.NewCond(&s.mu)
+	s.taskLocks.freed = s.wakeWorkers
+	s.stopCtx, s.stopRuns = context.WithCancelCause(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.setupPushSigner()
+	s.setupPushDelivery()
+	s.recoverTasks()
+	return s
+}
+
+// recoverTasks fails tasks that a previous process left running. Their handlers died with
+// that process, so without this they would report "working" forever.
+func (s *A2AServer) recoverTasks() {
+	tasks, err := s.store.List()
+	if err != nil {
+		s.logger.Printf("Error listing tasks for recovery: %v", err)
+		return
+	}
+	for _, task := range tasks {
+		if task.Status.State != models.TaskStateWorking && task.Status.State != models.TaskStateSubmitted {
+			continue
+		}
+		s.setStatus(task, models.TaskStatus{State: models.TaskStateFailed})
+		if err := s.store.Put(task); err != nil {
+			s.logger.Printf("Error recovering task %s: %v", task.ID, err)
+		}
 	}
 }
 
-// Start starts the A2A server
+// Start starts the A2A server on its own Host, serving until the process exits
 func (s *A2AServer) Start() error {
-	mux := http.NewServeMux()
-	mux.Handle(s.basePath, s)
-	return http.ListenAndServe(fmt.Sprintf(":%d", s.port), mux)
+	host := NewHost(s.addr)
+	host.Handle(s.basePath, s)
+	return host.ListenAndServe(context.Background())
 }
 
 // ServeHTTP implements the http.Handler interface
 func (s *A2AServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Handle GET request to return Agent Card
-	if r.Method == http.MethodGet {
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(s.agentCard); err != nil {
-			fmt.Printf("Error encoding agent card: %v\n", err)
-		}
+	// Browsers ask before calling from another origin; the answer needs no credentials
+	if s.cors != nil && s.cors.handle(w, r) {
+		return
+	}
+
+	if isJWKSPath(r) {
+		s.serveJWKS(w, r)
+		return
+	}
+
+	// Handle GET request, or any request to the well-known path, to return Agent Card
+	if r.Method == http.MethodGet || isAgentCardPath(r) {
+		s.serveAgentCard(w, r)
 		return
 	}
 
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		w.Header().Set("Allow", "GET, POST")
+		s.writeJSON(w, http.StatusMethodNotAllowed, errorResponse(nullID, NewError(models.ErrorCodeInvalidRequest, "Method not allowed")))
 		return
 	}
 
-	var req models.JSONRPCRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		// Return JSON-RPC error response with ErrorCodeInvalidRequest
-		response := models.JSONRPCResponse{
-			JSONRPCMessage: models.JSONRPCMessage{
-				JSONRPC: "2.0",
-			},
-			Error: &models.JSONRPCError{
-				Code:    int(models.ErrorCodeInvalidRequest),
-				Message: "Invalid JSON: " + err.Error(),
-			},
+	// The card stays public so clients can learn how to authenticate; calls do not
+	if s.authenticate != nil {
+		ctx, err := s.authenticate(r)
+		if err != nil {
+			for _, challenge := range s.authChallenges() {
+				w.Header().Add("WWW-Authenticate", challenge)
+			}
+			s.writeJSON(w, http.StatusUnauthorized, errorResponse(nullID, authError(err)))
+			return
 		}
+		r = r.WithContext(ctx)
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			fmt.Printf("Error encoding response: %v\n", err)
-		}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodySize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		s.writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse(nullID, NewError(models.ErrorCodeInvalidRequest, "Request body too large").
+			WithData(map[string]int64{"limit": tooLarge.Limit})))
 		return
 	}
+	if err != nil {
+		s.sendError(w, nullID, models.ErrorCodeInvalidRequest, "Failed to read request: "+err.Error())
+		return
+	}
+	if !json.Valid(body) {
+		s.sendError(w, nullID, models.ErrorCodeParseError, "Invalid JSON")
+		return
+	}
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		s.serveBatch(w, r, trimmed)
+		return
+	}
+	s.serveRequest(w, r, body, false)
+}
 
-	parseTaskSendParams := func(req *models.JSONRPCRequest) (*models.TaskSendParams, error) {
-		var params models.TaskSendParams
-		paramsBytes, err := json.Marshal(req.Params)
-		if err != nil {
-			return nil, err
+// serveRequest decodes a single JSON-RPC request and runs it, writing the response to w.
+// Streaming methods are refused inside a batch, since a batch is answered all at once.
+func (s *A2AServer) serveRequest(w http.ResponseWriter, r *http.Request, body []byte, inBatch bool) {
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		s.writeError(w, nullID, NewError(models.ErrorCodeInvalidRequest, "Invalid request").WithData(err.Error()))
+		return
+	}
+	if !validID(req.ID) {
+		s.sendError(w, nullID, models.ErrorCodeInvalidRequest, "Invalid request id")
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.sendError(w, req.ID, models.ErrorCodeInvalidRequest, `Request must have jsonrpc "2.0" and a method`)
+		return
+	}
+	if inBatch && isStreamingMethod(req.Method) {
+		if !req.isNotification() {
+			s.sendError(w, req.ID, models.ErrorCodeInvalidRequest, "Streaming method "+req.Method+" cannot be used in a batch")
 		}
-		if err := json.Unmarshal(paramsBytes, &params); err != nil {
-			return nil, err
+		return
+	}
+
+	// Notifications are carried out, but must not be answered
+	if req.isNotification() {
+		s.dispatch(&notificationWriter{header: make(http.Header)}, r, &req.JSONRPCRequest, nil)
+		if !inBatch {
+			w.WriteHeader(http.StatusNoContent)
 		}
-		return &params, nil
+		return
+	}
+	s.dispatch(w, r, &req.JSONRPCRequest, req.ID)
+}
+
+// dispatch runs the method named by the request, answering with the given id
+func (s *A2AServer) dispatch(w http.ResponseWriter, r *http.Request, req *models.JSONRPCRequest, id json.RawMessage) {
+	// A draining server finishes the work it has but takes no new messages
+	if s.closing.Load() && (req.Method == "message/send" || req.Method == "message/stream") {
+		s.sendRPCError(w, id, errShuttingDown)
+		return
 	}
 
 	switch req.Method {
 	case "message/send":
-		_, err := parseTaskSendParams(&req)
-		if err != nil {
-			s.sendError(w, req.ID.(string), models.ErrorCodeInvalidRequest, "Invalid parameters")
-			return
-		}
-		s.handleTaskSend(w, &req, req.ID.(string))
+		s.handleTaskSend(w, r, req, id)
 	case "message/stream":
-		params, err := parseTaskSendParams(&req)
-		if err != nil {
-			s.sendError(w, req.ID.(string), models.ErrorCodeInvalidRequest, "Invalid parameters")
+		var params models.TaskSendParams
+		if err := decodeParams(req, &params); err != nil {
+			s.sendRPCError(w, id, err)
 			return
 		}
-		s.handleStreamingTask(w, r, *params)
+		if params.PushNotification != nil {
+			if !s.supportsPushNotifications() {
+				s.sendError(w, id, models.ErrorCodePushNotificationNotSupported, "Push notifications not supported")
+				return
+			}
+			if err := s.checkPushURL(params.PushNotification.URL, "pushNotification.url"); err != nil {
+				s.writeError(w, id, err)
+				return
+			}
+			s.setPushConfig(params.ID, *params.PushNotification)
+		}
+		s.handleStreamingTask(w, r, params, id)
 	case "tasks/get":
-		s.handleTaskGet(w, &req, req.ID.(string))
+		s.handleTaskGet(w, req, id)
 	case "tasks/cancel":
-		s.handleTaskCancel(w, &req, req.ID.(string))
+		s.handleTaskCancel(w, req, id)
+	case "tasks/resubscribe":
+		s.handleTaskResubscribe(w, r, req, id)
+	case "tasks/pushNotification/set":
+		s.handleSetTaskPushNotification(w, req, id)
+	case "tasks/pushNotification/get":
+		s.handleGetTaskPushNotification(w, req, id)
 	default:
-		s.sendError(w, req.ID.(string), models.ErrorCodeMethodNotFound, "Method not found")
+		s.sendError(w, id, models.ErrorCodeMethodNotFound, "Method not found")
 	}
 }
 
 // handleTaskSend handles the message/send method
-func (s *A2AServer) handleTaskSend(w http.ResponseWriter, req *models.JSONRPCRequest, id string) {
+func (s *A2AServer) handleTaskSend(w http.ResponseWriter, r *http.Request, req *models.JSONRPCRequest, id json.RawMessage) {
 	var params models.TaskSendParams
-	paramsBytes, err := json.Marshal(req.Params)
-	if err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
-		return
-	}
-	if err := json.Unmarshal(paramsBytes, &params); err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
+	if err := decodeParams(req, &params); err != nil {
+		s.sendRPCError(w, id, err)
 		return
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if params.PushNotification != nil {
+		if !s.supportsPushNotifications() {
+			s.sendError(w, id, models.ErrorCodePushNotificationNotSupported, "Push notifications not supported")
+			return
+		}
+		if err := s.checkPushURL(params.PushNotification.URL, "pushNotification.url"); err != nil {
+			s.writeError(w, id, err)
+			return
+		}
+		s.setPushConfig(params.ID, *params.PushNotification)
+	}
 
-	// Create new task
-	task := &models.Task{
-		ID: params.ID,
-		Status: models.TaskStatus{
-			State: models.TaskStateWorking,
-		},
+	// Non-blocking sends are queued for the worker pool and answered with the submitted task
+	if !s.isBlocking(&params) {
+		task, err := s.enqueueMessage(r.Context(), params)
+		if err != nil {
+			s.sendRPCError(w, id, err)
+			return
+		}
+		s.sendResponse(w, id, task)
+		return
 	}
 
-	// Process task
-	// Pass dummy update function
-	updatedTask, err := s.handler(task, &params.Message, func(event any) {})
+	// The handler stops if the client disconnects, since nobody is waiting for the result
+	updatedTask, err := s.processMessage(r.Context(), &params)
 	if err != nil {
-		s.sendError(w, id, models.ErrorCodeInternalError, err.Error())
+		s.sendRPCError(w, id, err)
 		return
 	}
 
-	// Store task and history
-	s.taskStore[task.ID] = updatedTask
-	s.taskHistory[task.ID] = append(s.taskHistory[task.ID], &params.Message)
-
 	// Send response
-	s.sendResponse(w, id, updatedTask)
+	result, err := s.withHistory(updatedTask, params.HistoryLength)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, "Failed to load history: "+err.Error())
+		return
+	}
+	s.sendResponse(w, id, result)
 }
 
 // handleTaskGet handles the tasks/get method
-func (s *A2AServer) handleTaskGet(w http.ResponseWriter, req *models.JSONRPCRequest, id string) {
+func (s *A2AServer) handleTaskGet(w http.ResponseWriter, req *models.JSONRPCRequest, id json.RawMessage) {
 	var params models.TaskQueryParams
-	paramsBytes, err := json.Marshal(req.Params)
+	if err := decodeParams(req, &params); err != nil {
+		s.sendRPCError(w, id, err)
+		return
+	}
+
+	task, err := s.store.Get(params.ID)
 	if err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
+		s.sendStoreError(w, id, err)
 		return
 	}
-	if err := json.Unmarshal(paramsBytes, &params); err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
+
+	result, err := s.withHistory(task, params.HistoryLength)
+	if err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, "Failed to load history: "+err.Error())
 		return
 	}
+	s.sendResponse(w, id, result)
+}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// handleTaskCancel handles the tasks/cancel method
+func (s *A2AServer) handleTaskCancel(w http.ResponseWriter, req *models.JSONRPCRequest, id json.RawMessage) {
+	var params models.TaskIDParams
+	if err := decodeParams(req, &params); err != nil {
+		s.sendRPCError(w, id, err)
+		return
+	}
 
-	task, exists := s.taskStore[params.ID]
-	if !exists {
-		s.sendError(w, id, models.ErrorCodeTaskNotFound, "Task not found")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, err := s.store.Get(params.ID)
+	if err != nil {
+		s.sendStoreError(w, id, err)
+		return
+	}
+	if err := checkTransition(task.ID, task.Status.State, models.TaskStateCanceled); err != nil {
+		s.sendError(w, id, models.ErrorCodeTaskNotCancelable, err.Error())
+		return
+	}
+
+	// A running handler publishes the canceled status itself, which drops the push config;
+	// otherwise nothing more will be sent for the task
+	if !s.cancelRun(params.ID) {
+		s.deletePushConfig(params.ID)
+	}
+
+	// Update task status to canceled
+	s.setStatus(task, models.TaskStatus{State: models.TaskStateCanceled})
+	if err := s.store.Put(task); err != nil {
+		s.sendError(w, id, models.ErrorCodeInternalError, "Failed to store task: "+err.Error())
 		return
 	}
 
 	s.sendResponse(w, id, task)
 }
 
-// handleTaskCancel handles the tasks/cancel method
-func (s *A2AServer) handleTaskCancel(w http.ResponseWriter, req *models.JSONRPCRequest, id string) {
-	var params models.TaskIDParams
-	paramsBytes, err := json.Marshal(req.Params)
+// handleSetTaskPushNotification handles the tasks/pushNotification/set method
+func (s *A2AServer) handleSetTaskPushNotification(w http.ResponseWriter, req *models.JSONRPCRequest, id json.RawMessage) {
+	if !s.supportsPushNotifications() {
+		s.sendError(w, id, models.ErrorCodePushNotificationNotSupported, "Push notifications not supported")
+		return
+	}
+
+	var params models.TaskPushNotificationConfig
+	if err := decodeParams(req, &params); err != nil {
+		s.sendRPCError(w, id, err)
+		return
+	}
+	if err := s.checkPushURL(params.PushNotificationConfig.URL, "pushNotificationConfig.url"); err != nil {
+		s.writeError(w, id, err)
+		return
+	}
+
+	task, err := s.store.Get(params.ID)
 	if err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
+		s.sendStoreError(w, id, err)
 		return
 	}
-	if err := json.Unmarshal(paramsBytes, &params); err != nil {
-		s.sendError(w, id, models.ErrorCodeInvalidRequest, "Invalid parameters")
+	if task.Status.State.IsTerminal() {
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "Task has finished and sends no more notifications")
 		return
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.setPushConfig(params.ID, params.PushNotificationConfig)
+
+	s.sendResponse(w, id, params)
+}
 
-	task, exists := s.taskStore[params.ID]
+// handleGetTaskPushNotification handles the tasks/pushNotification/get method
+func (s *A2AServer) handleGetTaskPushNotification(w http.ResponseWriter, req *models.JSONRPCRequest, id json.RawMessage) {
+	if !s.supportsPushNotifications() {
+		s.sendError(w, id, models.ErrorCodePushNotificationNotSupported, "Push notifications not supported")
+		return
+	}
+
+	var params models.TaskIDParams
+	if err := decodeParams(req, &params); err != nil {
+		s.sendRPCError(w, id, err)
+		return
+	}
+
+	if _, err := s.store.Get(params.ID); err != nil {
+		s.sendStoreError(w, id, err)
+		return
+	}
+
+	config, exists := s.getPushConfig(params.ID)
 	if !exists {
-		s.sendError(w, id, models.ErrorCodeTaskNotFound, "Task not found")
+		s.sendError(w, id, models.ErrorCodeInvalidParams, "No push notification config for task")
 		return
 	}
 
-	// Update task status to canceled
-	task.Status.State = models.TaskStateCanceled
-	s.taskStore[params.ID] = task
+	s.sendResponse(w, id, models.TaskPushNotificationConfig{
+		ID:                     params.ID,
+		PushNotificationConfig: *config,
+	})
+}
 
-	s.sendResponse(w, id, task)
+// recordAgentReply adds the agent's reply carried in the task status to the task's history,
+// so the conversation holds both sides in order
+func (s *A2AServer) recordAgentReply(task *models.Task) error {
+	if task.Status.Message == nil {
+		return nil
+	}
+	return s.store.AppendHistory(task.ID, task.Status.Message)
+}
+
+// prepareForStore readies a task returned by the handler for storage: artifacts streamed
+// during the run are kept, response-only history is dropped, and the reply gets the agent role.
+// Artifacts and a reply with malformed parts are dropped, since the store could not read them
+// back; the first problem is returned so the run can fail with it.
+func prepareForStore(running, returned *models.Task) error {
+	keepArtifacts(running, returned)
+	// The timeline is kept by the server, whatever the handler returned
+	returned.StatusHistory = running.StatusHistory
+	returned.History = nil
+	if returned.Status.Message != nil && returned.Status.Message.Role == "" {
+		returned.Status.Message.Role = "agent"
+	}
+
+	var invalid error
+	var kept []models.Artifact
+	for _, artifact := range returned.Artifacts {
+		if err := checkParts("artifact", artifact.Parts); err != nil {
+			invalid = cmp.Or(invalid, err)
+			continue
+		}
+		kept = append(kept, artifact)
+	}
+	if len(kept) != len(returned.Artifacts) {
+		returned.Artifacts = kept
+	}
+	if err := checkStatusMessage(returned.Status); err != nil {
+		invalid = cmp.Or(invalid, err)
+		returned.Status.Message = nil
+	}
+	return invalid
+}
+
+// withHistory returns a copy of the task carrying its most recent historyLength messages.
+// A nil historyLength includes the whole conversation; zero or less includes none.
+func (s *A2AServer) withHistory(task *models.Task, historyLength *int) (*models.Task, error) {
+	result := *task
+	result.History = nil
+	if historyLength != nil && *historyLength <= 0 {
+		return &result, nil
+	}
+
+	history, err := s.store.History(task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if historyLength != nil && *historyLength < len(history) {
+		history = history[len(history)-*historyLength:]
+	}
+	for _, message := range history {
+		result.History = append(result.History, *message)
+	}
+	return &result, nil
+}
+
+// sendStoreError reports a task store failure, distinguishing a missing task from other errors
+func (s *A2AServer) sendStoreError(w http.ResponseWriter, id json.RawMessage, err error) {
+	if errors.Is(err, ErrTaskNotFound) {
+		s.sendError(w, id, models.ErrorCodeTaskNotFound, "Task not found")
+		return
+	}
+	s.sendError(w, id, models.ErrorCodeInternalError, "Failed to load task: "+err.Error())
 }
 
 // sendResponse sends a JSON-RPC response
-func (s *A2AServer) sendResponse(w http.ResponseWriter, id string, result interface{}) {
+func (s *A2AServer) sendResponse(w http.ResponseWriter, id json.RawMessage, result interface{}) {
 	response := models.JSONRPCResponse{
 		JSONRPCMessage: models.JSONRPCMessage{
 			JSONRPC: "2.0",
@@ -219,16 +553,22 @@ func (s *A2AServer) sendResponse(w http.ResponseWriter, id string, result interf
 		},
 		Result: result,
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		fmt.Printf("Error encoding response: %v\n", err)
-	}
+	s.writeJSON(w, http.StatusOK, response)
 }
 
 // sendError sends a JSON-RPC error response
-func (s *A2AServer) sendError(w http.ResponseWriter, id string, code models.ErrorCode, message string) {
-	response := models.JSONRPCResponse{
+func (s *A2AServer) sendError(w http.ResponseWriter, id json.RawMessage, code models.ErrorCode, message string) {
+	s.writeError(w, id, NewError(code, message))
+}
+
+// writeError sends rpcErr as a JSON-RPC error response
+func (s *A2AServer) writeError(w http.ResponseWriter, id json.RawMessage, rpcErr *Error) {
+	s.writeJSON(w, http.StatusOK, errorResponse(id, rpcErr))
+}
+
+// errorResponse builds the JSON-RPC response reporting rpcErr
+func errorResponse(id json.RawMessage, rpcErr *Error) models.JSONRPCResponse {
+	return models.JSONRPCResponse{
 		JSONRPCMessage: models.JSONRPCMessage{
 			JSONRPC: "2.0",
 			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
@@ -236,24 +576,25 @@ func (s *A2AServer) sendError(w http.ResponseWriter, id string, code models.Erro
 			},
 		},
 		Error: &models.JSONRPCError{
-			Code:    int(code),
-			Message: message,
+			Code:    int(rpcErr.Code),
+			Message: rpcErr.Message,
+			Data:    rpcErr.Data,
 		},
 	}
+}
 
+// writeJSON writes a JSON-RPC response body. Responses to calls, errors included, go out as
+// 200 OK since the outcome of the call is in the body; other statuses are reserved for
+// problems with the HTTP request itself.
+func (s *A2AServer) writeJSON(w http.ResponseWriter, status int, response any) {
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		fmt.Printf("Error encoding response: %v\n", err)
+		s.logger.Printf("Error encoding response: %v", err)
 	}
 }
 
-func (s *A2AServer) handleStreamingTask(w http.ResponseWriter, r *http.Request, params models.TaskSendParams) {
-	// Set headers for SSE
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
+func (s *A2AServer) handleStreamingTask(w http.ResponseWriter, r *http.Request, params models.TaskSendParams, id json.RawMessage) {
 	// Check if response writer supports flushing
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -261,102 +602,89 @@ func (s *A2AServer) handleStreamingTask(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	// Create a channel to receive task updates
-	updates := make(chan any)
+	// Every message/stream carries a message to process, so Last-Event-ID is not honored here;
+	// a client that lost its stream resumes it with tasks/resubscribe
 
-	// Create a done channel to signal when the goroutine is finished
-	done := make(chan struct{})
+	// Wait for earlier messages to this task to finish; the lock is held until the run ends
+	unlock, err := s.taskLocks.lock(r.Context(), params.ID)
+	if err != nil {
+		s.sendRPCError(w, id, err)
+		return
+	}
+
+	// Start the task before committing to SSE so a rejected message gets a plain error response
+	// The run is detached from this connection: a client that drops can reattach with
+	// tasks/resubscribe, so only tasks/cancel or the handler timeout stop the handler
+	task, ctx, done, err := s.beginTask(context.WithoutCancel(r.Context()), &params)
+	if err != nil {
+		unlock()
+		s.sendRPCError(w, id, err)
+		return
+	}
+
+	// Events go to a per-task log rather than straight to this connection, so the task
+	// keeps running if the client drops and can be picked up again via tasks/resubscribe
+	log := s.startEventLog(params.ID)
+	setSSEHeaders(w)
 
 	// Start task processing in a goroutine
 	go func() {
-		defer func() {
-			close(updates) // Close updates channel when goroutine exits
-			close(done)    // Signal that goroutine is done
-		}()
+		defer unlock()
+		defer s.endEventLog(params.ID, log)
+		defer done()
 
-		// Recover from any panics to ensure channels are closed
+		// Recover from any panics to ensure the log is closed
 		defer func() {
 			if r := recover(); r != nil {
 				// Log the panic (you might want to use a proper logger)
-				fmt.Printf("Recovered from panic in streaming task: %v\n", r)
+				s.logger.Printf("Recovered from panic in streaming task: %v", r)
 			}
 		}()
 
-		s.mu.Lock()
-		// Create new task
-		task := &models.Task{
-			ID: params.ID,
-			Status: models.TaskStatus{
-				State: models.TaskStateWorking,
-			},
+		if _, err := s.runTask(ctx, log, task, &params.Message); err != nil {
+			s.logger.Printf("Streaming task %s failed: %v", task.ID, err)
 		}
-		s.taskStore[task.ID] = task
-		s.taskHistory[task.ID] = append(s.taskHistory[task.ID], &params.Message)
-		s.mu.Unlock()
+	}()
 
-		// Send initial status update
-		updates <- models.TaskStatusUpdateEvent{
-			ID:     task.ID,
-			Status: task.Status,
-			Final:  boolPtr(false),
-		}
+	// Stream updates to the client
+	s.streamEvents(r.Context(), w, flusher, log, 0, id)
+}
 
-		// Define the update callback
-		updateFunc := func(event any) {
-			updates <- event
-		}
+// handleTaskResubscribe handles the tasks/resubscribe method. It replays the events of the
+// task's latest run, from the beginning or after the client's Last-Event-ID, and then
+// follows the live ones until the run ends.
+func (s *A2AServer) handleTaskResubscribe(w http.ResponseWriter, r *http.Request, req *models.JSONRPCRequest, id json.RawMessage) {
+	var params models.TaskQueryParams
+	if err := decodeParams(req, &params); err != nil {
+		s.sendRPCError(w, id, err)
+		return
+	}
 
-		// Process task using the handler field
-		updatedTask, err := s.handler(task, &params.Message, updateFunc)
-		if err != nil {
-			// Send error status update
-			updates <- models.TaskStatusUpdateEvent{
-				ID: task.ID,
-				Status: models.TaskStatus{
-					State: models.TaskStateFailed,
-				},
-				Final: boolPtr(true),
-			}
-			return
-		}
+	task, err := s.store.Get(params.ID)
+	if err != nil {
+		s.sendStoreError(w, id, err)
+		return
+	}
 
-		// Update task in store
-		s.mu.Lock()
-		s.taskStore[task.ID] = updatedTask
-		s.mu.Unlock()
+	setSSEHeaders(w)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
 
-		// Send final status update
-		updates <- models.TaskStatusUpdateEvent{
-			ID:     updatedTask.ID,
-			Status: updatedTask.Status,
+	log, exists := s.getEventLog(params.ID)
+	if !exists {
+		// No recorded run to replay, so report the current status as the final event
+		log = newEventLog()
+		log.append(models.TaskStatusUpdateEvent{
+			ID:     params.ID,
+			Status: task.Status,
 			Final:  boolPtr(true),
-		}
-	}()
-
-	// Stream updates to the client
-	encoder := json.NewEncoder(w)
-	for {
-		select {
-		case update, ok := <-updates:
-			if !ok {
-				// Channel closed, we're done
-				return
-			}
-			resp := models.SendTaskStreamingResponse{
-				Result: update,
-				Error:  nil,
-			}
-
-			if err := encoder.Encode(resp); err != nil {
-				return
-			}
-			flusher.Flush()
-		case <-r.Context().Done():
-			// Client disconnected
-			return
-		case <-done:
-			// Goroutine finished
-			return
-		}
+		})
+		log.close()
 	}
+
+	from, _ := lastEventID(r)
+	s.streamEvents(r.Context(), w, flusher, log, from, id)
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,13 +14,19 @@ import (
 )
 
 // mockTaskHandler is a simple task handler for testing
-func mockTaskHandler(task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+func mockTaskHandler(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
 	task.Status.State = models.TaskStateCompleted
 	return task, nil
 }
 
+// mockInputTaskHandler is a task handler that keeps the conversation open for testing
+func mockInputTaskHandler(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+	task.Status.State = models.TaskStateInputRequired
+	return task, nil
+}
+
 // mockErrorTaskHandler is a task handler that returns an error for testing
-func mockErrorTaskHandler(task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+func mockErrorTaskHandler(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
 	return nil, fmt.Errorf("test error")
 }
 
@@ -63,9 +70,7 @@ func (w *mockNonFlushingResponseWriter) WriteHeader(statusCode int) {
 }
 
 func TestA2AServer_HandleTaskSend(t *testing.T) {
-	server := NewA2AServer(mockAgentCard, mockTaskHandler)
-	server.port = 8080
-	server.basePath = "/"
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithListenAddress(":8080"), WithBasePath("/"))
 
 	// Create a test request
 	params := models.TaskSendParams{
@@ -129,9 +134,7 @@ func TestA2AServer_HandleTaskSend(t *testing.T) {
 }
 
 func TestA2AServer_HandleTaskGet(t *testing.T) {
-	server := NewA2AServer(mockAgentCard, mockTaskHandler)
-	server.port = 8080
-	server.basePath = "/"
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithListenAddress(":8080"), WithBasePath("/"))
 
 	// First create a task
 	params := models.TaskSendParams{
@@ -215,9 +218,7 @@ func TestA2AServer_HandleTaskGet(t *testing.T) {
 }
 
 func TestA2AServer_HandleTaskCancel(t *testing.T) {
-	server := NewA2AServer(mockAgentCard, mockTaskHandler)
-	server.port = 8080
-	server.basePath = "/"
+	server := NewA2AServer(mockAgentCard, mockInputTaskHandler, WithListenAddress(":8080"), WithBasePath("/"))
 
 	// First create a task
 	params := models.TaskSendParams{
@@ -303,9 +304,7 @@ func TestA2AServer_HandleTaskCancel(t *testing.T) {
 }
 
 func TestErrorResponse(t *testing.T) {
-	server := NewA2AServer(mockAgentCard, mockTaskHandler)
-	server.port = 8080
-	server.basePath = "/"
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithListenAddress(":8080"), WithBasePath("/"))
 
 	// Test with invalid JSON
 	req := httptest.NewRequest("POST", "/", bytes.NewBufferString("invalid json"))
@@ -327,15 +326,13 @@ func TestErrorResponse(t *testing.T) {
 		t.Error("Expected error, got nil")
 	}
 
-	if response.Error.Code != int(models.ErrorCodeInvalidRequest) {
-		t.Errorf("Expected error code %d, got %d", models.ErrorCodeInvalidRequest, response.Error.Code)
+	if response.Error.Code != int(models.ErrorCodeParseError) {
+		t.Errorf("Expected error code %d, got %d", models.ErrorCodeParseError, response.Error.Code)
 	}
 }
 
 func TestA2AServer_HandleStreamingTask(t *testing.T) {
-	server := NewA2AServer(mockAgentCard, mockTaskHandler)
-	server.port = 8080
-	server.basePath = "/"
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithListenAddress(":8080"), WithBasePath("/"))
 
 	// Create a test request
 	params := models.TaskSendParams{
@@ -382,10 +379,10 @@ func TestA2AServer_HandleStreamingTask(t *testing.T) {
 	}
 
 	// Parse the streaming response
-	// The response should contain multiple JSON objects, one per line
-	responseLines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	// The response should contain multiple JSON objects, one per SSE event
+	responseLines := streamData(t, w.Body.String())
 	if len(responseLines) < 2 {
-		t.Errorf("Expected at least 2 response lines, got %d", len(responseLines))
+		t.Fatalf("Expected at least 2 stream events, got %d", len(responseLines))
 	}
 
 	// Check the initial status update
@@ -397,6 +394,9 @@ func TestA2AServer_HandleStreamingTask(t *testing.T) {
 	if initialResponse.Error != nil {
 		t.Errorf("Expected no error in initial response, got %v", initialResponse.Error)
 	}
+	if initialResponse.JSONRPC != "2.0" || initialResponse.ID != "1" {
+		t.Errorf("Expected a JSON-RPC 2.0 envelope for request 1, got jsonrpc %q id %v", initialResponse.JSONRPC, initialResponse.ID)
+	}
 
 	// Check that the result is a TaskStatusUpdateEvent
 	initialResultBytes, err := json.Marshal(initialResponse.Result)
@@ -456,9 +456,7 @@ func TestA2AServer_HandleStreamingTask(t *testing.T) {
 }
 
 func TestA2AServer_HandleStreamingTaskError(t *testing.T) {
-	server := NewA2AServer(mockAgentCard, mockErrorTaskHandler)
-	server.port = 8080
-	server.basePath = "/"
+	server := NewA2AServer(mockAgentCard, mockErrorTaskHandler, WithListenAddress(":8080"), WithBasePath("/"))
 
 	// Create a test request
 	params := models.TaskSendParams{
@@ -499,10 +497,10 @@ func TestA2AServer_HandleStreamingTaskError(t *testing.T) {
 	}
 
 	// Parse the streaming response
-	// The response should contain multiple JSON objects, one per line
-	responseLines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	// The response should contain multiple JSON objects, one per SSE event
+	responseLines := streamData(t, w.Body.String())
 	if len(responseLines) < 2 {
-		t.Errorf("Expected at least 2 response lines, got %d", len(responseLines))
+		t.Fatalf("Expected at least 2 stream events, got %d", len(responseLines))
 	}
 
 	// Check the initial status update
@@ -538,6 +536,18 @@ func TestA2AServer_HandleStreamingTaskError(t *testing.T) {
 		t.Error("Expected Final to be false for initial update")
 	}
 
+	// The failure reason arrives as a JSON-RPC error event just before the final status
+	var errorResponse models.SendTaskStreamingResponse
+	if err := json.Unmarshal([]byte(responseLines[len(responseLines)-2]), &errorResponse); err != nil {
+		t.Fatalf("Failed to unmarshal error response: %v", err)
+	}
+	if errorResponse.Error == nil || errorResponse.Error.Code != models.ErrorCodeInternalError || errorResponse.Error.Message != "test error" {
+		t.Errorf("Expected internal error event with the handler's error, got %+v", errorResponse.Error)
+	}
+	if errorResponse.Result != nil {
+		t.Errorf("Expected no result in error event, got %v", errorResponse.Result)
+	}
+
 	// Check the error status update
 	var finalResponse models.SendTaskStreamingResponse
 	if err := json.Unmarshal([]byte(responseLines[len(responseLines)-1]), &finalResponse); err != nil {
@@ -573,9 +583,7 @@ func TestA2AServer_HandleStreamingTaskError(t *testing.T) {
 }
 
 func TestA2AServer_HandleStreamingTaskNoFlusher(t *testing.T) {
-	server := NewA2AServer(mockAgentCard, mockTaskHandler)
-	server.port = 8080
-	server.basePath = "/"
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithListenAddress(":8080"), WithBasePath("/"))
 
 	// Create a test request
 	params := models.TaskSendParams{
@@ -616,3 +624,136 @@ func TestA2AServer_HandleStreamingTaskNoFlusher(t *testing.T) {
 		t.Errorf("Expected error message 'Streaming not supported', got '%s'", w.body.String())
 	}
 }
+
+// newRPCRequest builds an HTTP request carrying a JSON-RPC call
+func newRPCRequest(method string, params any) *http.Request {
+	reqBody, _ := json.Marshal(models.JSONRPCRequest{
+		JSONRPCMessage: models.JSONRPCMessage{
+			JSONRPC: "2.0",
+			JSONRPCMessageIdentifier: models.JSONRPCMessageIdentifier{
+				ID: "1",
+			},
+		},
+		Method: method,
+		Params: params,
+	})
+
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// doRPC sends a JSON-RPC request to the server and decodes the response
+func doRPC(t *testing.T, server *A2AServer, method string, params any) models.JSONRPCResponse {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, newRPCRequest(method, params))
+
+	var response models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+// decodeResult re-decodes a generic JSON-RPC result into a typed value
+func decodeResult(t *testing.T, result any, v any) {
+	t.Helper()
+
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to marshal result: %v", err)
+	}
+	if err := json.Unmarshal(resultBytes, v); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+}
+
+func TestA2AServer_HistoryLength(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockInputTaskHandler)
+
+	for _, text := range []string{"first", "second", "third"} {
+		doRPC(t, server, "message/send", models.TaskSendParams{
+			ID:      "test-task-1",
+			Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr(text)}}},
+		})
+	}
+
+	tests := []struct {
+		name          string
+		historyLength *int
+		want          []string
+	}{
+		{"omitted returns everything", nil, []string{"first", "second", "third"}},
+		{"trimmed to most recent", intPtr(2), []string{"second", "third"}},
+		{"longer than history", intPtr(10), []string{"first", "second", "third"}},
+		{"zero returns none", intPtr(0), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := doRPC(t, server, "tasks/get", models.TaskQueryParams{
+				TaskIDParams:  models.TaskIDParams{ID: "test-task-1"},
+				HistoryLength: tt.historyLength,
+			})
+			if response.Error != nil {
+				t.Fatalf("Expected no error, got %v", response.Error)
+			}
+
+			var task models.Task
+			decodeResult(t, response.Result, &task)
+
+			var got []string
+			for _, message := range task.History {
+				got = append(got, *message.Parts[0].Text)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected history %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// message/send honors historyLength as well
+	response := doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:            "test-task-1",
+		Message:       models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("fourth")}}},
+		HistoryLength: intPtr(1),
+	})
+	var task models.Task
+	decodeResult(t, response.Result, &task)
+	if len(task.History) != 1 || *task.History[0].Parts[0].Text != "fourth" {
+		t.Errorf("Expected only the latest message, got %v", task.History)
+	}
+}
+
+func TestA2AServer_AgentReplyInHistory(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		task.Status.State = models.TaskStateCompleted
+		task.Status.Message = &models.Message{Parts: []models.Part{{Text: stringPtr("Hi there")}}}
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	response := doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+	})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+
+	var task models.Task
+	decodeResult(t, response.Result, &task)
+
+	reply := task.Status.Message
+	if reply == nil || reply.Role != "agent" || *reply.Parts[0].Text != "Hi there" {
+		t.Fatalf("Expected agent reply in status message, got %v", reply)
+	}
+	if len(task.History) != 2 {
+		t.Fatalf("Expected user and agent messages in history, got %d", len(task.History))
+	}
+	if task.History[0].Role != "user" || task.History[1].Role != "agent" {
+		t.Errorf("Expected history roles user, agent, got %s, %s", task.History[0].Role, task.History[1].Role)
+	}
+}
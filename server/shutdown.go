@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"a2a/models"
+)
+
+// stopGracePeriod bounds how long Shutdown waits, once it has canceled what was left, for the
+// canceled runs to store their tasks and for undelivered notifications to be dead-lettered
+const stopGracePeriod = 5 * time.Second
+
+// errShuttingDown rejects messages that arrive while the server is draining
+var errShuttingDown = NewError(models.ErrorCodeInternalError, "Server is shutting down")
+
+// errServerShutdown is recorded as the cancellation cause of runs cut short by Shutdown
+var errServerShutdown = errors.New("server shut down before the task finished")
+
+// Shutdown drains the server: it stops taking new messages, lets the worker pool finish the
+// messages already queued and waits for running handlers to return, which also ends the
+// streams following them, and for their push notifications to be delivered. If ctx expires
+// first, the remaining runs are canceled, their tasks fail, undelivered notifications are
+// dead-lettered, and ctx's error is returned once that is done or stopGracePeriod has passed.
+// Requests for existing tasks are still answered.
+func (s *A2AServer) Shutdown(ctx context.Context) error {
+	// enqueueMessage checks closing under s.mu, so nothing is added to the queue from here on,
+	// and idle workers stop once the queue is empty
+	s.mu.Lock()
+	s.closing.Store(true)
+	s.queueChanged.Broadcast()
+	s.mu.Unlock()
+
+	if err := s.waitForIdle(ctx); err != nil {
+		s.abandon()
+		return err
+	}
+	return nil
+}
+
+// abandon cancels the remaining runs and notification retries, then gives them a moment to
+// wind down so that their last writes reach the stores before Close releases them
+func (s *A2AServer) abandon() {
+	s.stopRuns(errServerShutdown)
+
+	ctx, cancel := context.WithTimeout(context.Background(), stopGracePeriod)
+	defer cancel()
+	if err := s.waitForIdle(ctx); err != nil {
+		s.logger.Printf("Canceled runs and deliveries did not finish within %s: %v", stopGracePeriod, err)
+	}
+}
+
+// waitForIdle blocks until the workers have finished the queued messages, no handler is running
+// and every push notification queue has drained, or until ctx is done
+func (s *A2AServer) waitForIdle(ctx context.Context) error {
+	workersDone := make(chan struct{})
+	go func() {
+		s.workerGroup.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err := s.waitForRuns(ctx); err != nil {
+		return err
+	}
+	return s.waitForDeliveries(ctx)
+}
+
+// Close releases the resources given with WithClosers, the last one given first. Call it after
+// Shutdown; a Host does so once it has stopped serving.
+func (s *A2AServer) Close() error {
+	var errs []error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		errs = append(errs, s.closers[i].Close())
+	}
+	s.closers = nil
+	return errors.Join(errs...)
+}
+
+// waitForRuns blocks until no handler is running or ctx is done
+func (s *A2AServer) waitForRuns(ctx context.Context) error {
+	s.runsMu.Lock()
+	if len(s.runs) == 0 {
+		s.runsMu.Unlock()
+		return nil
+	}
+	if s.runsIdle == nil {
+		s.runsIdle = make(chan struct{})
+	}
+	idle := s.runsIdle
+	s.runsMu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitForDeliveries blocks until every push notification queue has drained or ctx is done
+func (s *A2AServer) waitForDeliveries(ctx context.Context) error {
+	s.deliveryMu.Lock()
+	if len(s.deliveryQueues) == 0 {
+		s.deliveryMu.Unlock()
+		return nil
+	}
+	if s.deliveriesIdle == nil {
+		s.deliveriesIdle = make(chan struct{})
+	}
+	idle := s.deliveriesIdle
+	s.deliveryMu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
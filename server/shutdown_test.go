@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+func TestA2AServer_ShutdownDrainsQueue(t *testing.T) {
+	started, release := make(chan string, 2), make(chan struct{})
+	server := NewA2AServer(mockAgentCard, gatedHandler(started, release), WithAsyncSend(true), WithWorkerPool(1, 10))
+
+	for _, id := range []string{"test-task-1", "test-task-2"} {
+		if response := doRPC(t, server, "message/send", sendParams(id, "Hello")); response.Error != nil {
+			t.Fatalf("Expected no error, got %v", response.Error)
+		}
+	}
+	<-started
+
+	done := make(chan error, 1)
+	go func() { done <- server.Shutdown(context.Background()) }()
+
+	// New messages are refused while draining, but existing tasks can still be read
+	waitUntil(t, server.closing.Load)
+	response := doRPC(t, server, "message/send", sendParams("test-task-3", "Hello"))
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Errorf("Expected a shutting down error, got %+v", response.Error)
+	}
+	if state := getState(t, server, "test-task-2"); state != models.TaskStateSubmitted {
+		t.Errorf("Expected queued task to be %s, got %s", models.TaskStateSubmitted, state)
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("Shutdown returned before the queue drained: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	for _, id := range []string{"test-task-1", "test-task-2"} {
+		if state := getState(t, server, id); state != models.TaskStateCompleted {
+			t.Errorf("Expected task %s to be %s, got %s", id, models.TaskStateCompleted, state)
+		}
+	}
+}
+
+func TestA2AServer_ShutdownTimeout(t *testing.T) {
+	started := make(chan string, 1)
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		started <- task.ID
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	server := NewA2AServer(mockAgentCard, handler, WithAsyncSend(true))
+
+	if response := doRPC(t, server, "message/send", sendParams("test-task-1", "Hello")); response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+
+	// The run is canceled and its task fails rather than being left working
+	waitForState(t, server, "test-task-1", models.TaskStateFailed)
+}
+
+// waitUntil polls cond until it holds
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestA2AServer_ShutdownTimeoutKeepsFinalWrites(t *testing.T) {
+	hung := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-hung:
+		case <-r.Context().Done():
+		}
+	}))
+	defer receiver.Close()
+	defer close(hung)
+
+	dir := t.TempDir()
+	store, err := NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	deadLetterPath := filepath.Join(dir, deadLetterFileName)
+	deadLetters, err := NewFileDeadLetterStore(deadLetterPath)
+	if err != nil {
+		t.Fatalf("Failed to open dead letters: %v", err)
+	}
+
+	started := make(chan string, 1)
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		if task.ID == "test-task-2" {
+			started <- task.ID
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(pushAgentCard(), handler,
+		WithTaskStore(store), WithDeadLetterStore(deadLetters), WithPushDelivery(fastRetries),
+		WithClosers(store, deadLetters))
+
+	sendWithPush(t, server, receiver.URL)
+	params := sendParams("test-task-2", "Hello")
+	params.Blocking = boolPtr(false)
+	if response := doRPC(t, server, "message/send", params); response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if err := server.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// What the canceled work wrote on its way out made it to disk
+	deadLetters, err = NewFileDeadLetterStore(deadLetterPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen dead letters: %v", err)
+	}
+	defer func() { _ = deadLetters.Close() }()
+	letters, _ := deadLetters.List()
+	if metrics := server.DeliveryMetrics(); metrics.DeadLettered == 0 || int64(len(letters)) != metrics.DeadLettered {
+		t.Errorf("Expected %d dead letters on disk, got %d", metrics.DeadLettered, len(letters))
+	}
+
+	store, err = NewFileTaskStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	task, err := store.Get("test-task-2")
+	if err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	if task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected the canceled task to be stored as %s, got %s", models.TaskStateFailed, task.Status.State)
+	}
+}
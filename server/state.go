@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"a2a/models"
+)
+
+// transitionError reports a task state change that the state machine does not allow
+type transitionError struct {
+	taskID string
+	from   models.TaskState
+	to     models.TaskState
+}
+
+func (e *transitionError) Error() string {
+	if e.from.IsTerminal() {
+		return fmt.Sprintf("task %s is already %s", e.taskID, e.from)
+	}
+	return fmt.Sprintf("task %s cannot move from %s to %s", e.taskID, e.from, e.to)
+}
+
+// checkTransition returns a *transitionError if the task may not move from one state to the other
+func checkTransition(taskID string, from, to models.TaskState) error {
+	if !from.CanTransitionTo(to) {
+		return &transitionError{taskID: taskID, from: from, to: to}
+	}
+	return nil
+}
+
+// recordsTransitions reports whether the agent card advertises state transition history
+func (s *A2AServer) recordsTransitions() bool {
+	return s.agentCard.Capabilities.StateTransitionHistory != nil && *s.agentCard.Capabilities.StateTransitionHistory
+}
+
+// setStatus moves the task to status, stamping the time of the change. When the agent keeps
+// state transition history the new status is also added to the task's timeline, unless it
+// repeats the latest entry, in which case the task keeps the time that status was entered.
+func (s *A2AServer) setStatus(task *models.Task, status models.TaskStatus) {
+	status.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	if s.recordsTransitions() {
+		if n := len(task.StatusHistory); n > 0 && sameStatus(task.StatusHistory[n-1], status) {
+			status.Timestamp = task.StatusHistory[n-1].Timestamp
+		} else {
+			task.StatusHistory = append(task.StatusHistory, status)
+		}
+	}
+	task.Status = status
+}
+
+// recordStatus applies a status update published by the handler to the running task, so it
+// shows up in the task's timeline
+func (s *A2AServer) recordStatus(task *models.Task, event any) {
+	switch e := event.(type) {
+	case models.TaskStatusUpdateEvent:
+		s.setStatus(task, e.Status)
+	case *models.TaskStatusUpdateEvent:
+		s.setStatus(task, e.Status)
+	}
+}
+
+// sameStatus reports whether two statuses have the same state and message, whenever they were set
+func sameStatus(a, b models.TaskStatus) bool {
+	return a.State == b.State && reflect.DeepEqual(a.Message, b.Message)
+}
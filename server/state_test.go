@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+func TestA2AServer_CancelTerminalTask(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockTaskHandler)
+	doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("hello")}}},
+	})
+
+	response := doRPC(t, server, "tasks/cancel", models.TaskIDParams{ID: "test-task-1"})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeTaskNotCancelable) {
+		t.Fatalf("Expected task not cancelable error, got %v", response.Error)
+	}
+
+	task, err := server.store.Get("test-task-1")
+	if err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCompleted, task.Status.State)
+	}
+}
+
+func TestA2AServer_SendToTerminalTask(t *testing.T) {
+	message := models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("again")}}}
+
+	for _, blocking := range []bool{true, false} {
+		server := NewA2AServer(mockAgentCard, mockTaskHandler)
+		doRPC(t, server, "message/send", models.TaskSendParams{ID: "test-task-1", Message: message})
+
+		response := doRPC(t, server, "message/send", models.TaskSendParams{
+			ID:       "test-task-1",
+			Message:  message,
+			Blocking: &blocking,
+		})
+		if response.Error == nil || response.Error.Code != int(models.ErrorCodeUnsupportedOperation) {
+			t.Fatalf("blocking=%v: expected unsupported operation error, got %v", blocking, response.Error)
+		}
+
+		history, err := server.store.History("test-task-1")
+		if err != nil {
+			t.Fatalf("Failed to load history: %v", err)
+		}
+		if len(history) != 1 {
+			t.Errorf("blocking=%v: expected the rejected message to stay out of history, got %d messages", blocking, len(history))
+		}
+	}
+}
+
+func TestA2AServer_HandlerReturnsIllegalState(t *testing.T) {
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		task.Status.State = models.TaskStateSubmitted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	response := doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("hello")}}},
+	})
+	if response.Error == nil || response.Error.Code != int(models.ErrorCodeInternalError) {
+		t.Fatalf("Expected internal error, got %v", response.Error)
+	}
+
+	task, err := server.store.Get("test-task-1")
+	if err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	if task.Status.State != models.TaskStateFailed {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateFailed, task.Status.State)
+	}
+}
+
+func TestTaskState_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to models.TaskState
+		want     bool
+	}{
+		{"", models.TaskStateWorking, true},
+		{models.TaskStateSubmitted, models.TaskStateWorking, true},
+		{models.TaskStateWorking, models.TaskStateInputRequired, true},
+		{models.TaskStateInputRequired, models.TaskStateWorking, true},
+		{models.TaskStateWorking, models.TaskStateSubmitted, false},
+		{models.TaskStateCompleted, models.TaskStateWorking, false},
+		{models.TaskStateCompleted, models.TaskStateCanceled, false},
+		{models.TaskStateCanceled, models.TaskStateCanceled, false},
+		{models.TaskStateFailed, models.TaskStateSubmitted, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%q -> %q: expected %v, got %v", tt.from, tt.to, tt.want, got)
+		}
+	}
+}
+
+func TestA2AServer_StatusHistory(t *testing.T) {
+	server := NewA2AServer(mockAgentCard, mockInputTaskHandler)
+	message := models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("hello")}}}
+	doRPC(t, server, "message/send", models.TaskSendParams{ID: "test-task-1", Message: message})
+	doRPC(t, server, "message/send", models.TaskSendParams{ID: "test-task-1", Message: message})
+	doRPC(t, server, "tasks/cancel", models.TaskIDParams{ID: "test-task-1"})
+
+	response := doRPC(t, server, "tasks/get", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "test-task-1"}})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %v", response.Error)
+	}
+	var task models.Task
+	decodeResult(t, response.Result, &task)
+
+	want := []models.TaskState{
+		models.TaskStateWorking,
+		models.TaskStateInputRequired,
+		models.TaskStateWorking,
+		models.TaskStateInputRequired,
+		models.TaskStateCanceled,
+	}
+	if len(task.StatusHistory) != len(want) {
+		t.Fatalf("Expected %d transitions, got %v", len(want), task.StatusHistory)
+	}
+	var previous time.Time
+	for i, status := range task.StatusHistory {
+		if status.State != want[i] {
+			t.Errorf("Transition %d: expected %s, got %s", i, want[i], status.State)
+		}
+		at, err := time.Parse(time.RFC3339Nano, status.Timestamp)
+		if err != nil {
+			t.Fatalf("Transition %d: bad timestamp %q: %v", i, status.Timestamp, err)
+		}
+		if at.Before(previous) {
+			t.Errorf("Transition %d: timestamp %s is before %s", i, at, previous)
+		}
+		previous = at
+	}
+	if task.Status.Timestamp != task.StatusHistory[len(want)-1].Timestamp {
+		t.Errorf("Expected current status to match the last transition, got %v", task.Status)
+	}
+}
+
+func TestA2AServer_StatusHistoryHandlerUpdates(t *testing.T) {
+	progress := &models.Message{Role: "agent", Parts: []models.Part{{Text: stringPtr("Searching")}}}
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		// Repeating the current status adds nothing; a new message does
+		update(models.TaskStatusUpdateEvent{ID: task.ID, Status: models.TaskStatus{State: models.TaskStateWorking}})
+		update(&models.TaskStatusUpdateEvent{ID: task.ID, Status: models.TaskStatus{State: models.TaskStateWorking, Message: progress}})
+		update(models.TaskStatusUpdateEvent{ID: task.ID, Status: models.TaskStatus{State: models.TaskStateWorking, Message: progress}})
+		task.Status.State = models.TaskStateCompleted
+		task.Status.Message = nil
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+	doRPC(t, server, "message/send", sendParams("test-task-1", "hello"))
+
+	task, err := server.store.Get("test-task-1")
+	if err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	var got []string
+	for _, status := range task.StatusHistory {
+		label := string(status.State)
+		if status.Message != nil {
+			label += "(" + *status.Message.Parts[0].Text + ")"
+		}
+		got = append(got, label)
+	}
+	if want := "working,working(Searching),completed"; strings.Join(got, ",") != want {
+		t.Errorf("Expected timeline %s, got %s", want, strings.Join(got, ","))
+	}
+}
+
+func TestA2AServer_StatusHistoryNotSupported(t *testing.T) {
+	card := mockAgentCard
+	card.Capabilities.StateTransitionHistory = nil
+	server := NewA2AServer(card, mockTaskHandler)
+	doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("hello")}}},
+	})
+
+	task, err := server.store.Get("test-task-1")
+	if err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	if len(task.StatusHistory) != 0 {
+		t.Errorf("Expected no status history, got %v", task.StatusHistory)
+	}
+	if task.Status.Timestamp == "" {
+		t.Error("Expected the status to carry a timestamp")
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"errors"
+	"maps"
+	"sort"
+	"sync"
+
+	"a2a/models"
+)
+
+// ErrTaskNotFound is returned by a TaskStore when no task exists for the given ID
+var ErrTaskNotFound = errors.New("task not found")
+
+// TaskStore persists tasks and their message history.
+// Implementations must be safe for concurrent use.
+type TaskStore interface {
+	// Get returns the task with the given ID, or ErrTaskNotFound
+	Get(id string) (*models.Task, error)
+	// Put creates or replaces a task
+	Put(task *models.Task) error
+	// AppendHistory appends a message to the history of the task with the given ID
+	AppendHistory(id string, message *models.Message) error
+	// History returns the messages recorded for a task in chronological order
+	History(id string) ([]*models.Message, error)
+	// List returns all stored tasks ordered by ID
+	List() ([]*models.Task, error)
+	// Delete removes a task and its history
+	Delete(id string) error
+}
+
+// MemoryTaskStore is a TaskStore that keeps everything in process memory
+type MemoryTaskStore struct {
+	tasks   map[string]*models.Task
+	history map[string][]*models.Message
+	mu      sync.RWMutex
+}
+
+// NewMemoryTaskStore creates an empty in-memory task store
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{
+		tasks:   make(map[string]*models.Task),
+		history: make(map[string][]*models.Message),
+	}
+}
+
+// Get returns a copy of the task with the given ID
+func (m *MemoryTaskStore) Get(id string) (*models.Task, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	task, exists := m.tasks[id]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	return cloneTask(task), nil
+}
+
+// Put stores a copy of the task, so later changes by the caller are not visible to readers
+func (m *MemoryTaskStore) Put(task *models.Task) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tasks[task.ID] = cloneTask(task)
+	return nil
+}
+
+// AppendHistory appends a message to the task's history
+func (m *MemoryTaskStore) AppendHistory(id string, message *models.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.history[id] = append(m.history[id], cloneMessage(message))
+	return nil
+}
+
+// History returns the messages recorded for a task
+func (m *MemoryTaskStore) History(id string) ([]*models.Message, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := make([]*models.Message, len(m.history[id]))
+	for i, message := range m.history[id] {
+		history[i] = cloneMessage(message)
+	}
+	return history, nil
+}
+
+// List returns copies of all stored tasks ordered by ID
+func (m *MemoryTaskStore) List() ([]*models.Task, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tasks := make([]*models.Task, 0, len(m.tasks))
+	for _, task := range m.tasks {
+		tasks = append(tasks, cloneTask(task))
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+// Delete removes a task and its history
+func (m *MemoryTaskStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.tasks[id]; !exists {
+		return ErrTaskNotFound
+	}
+	delete(m.tasks, id)
+	delete(m.history, id)
+	return nil
+}
+
+// cloneTask copies a task down to its parts, so that a handler extending an artifact or the
+// history in place cannot race with readers of the stored task
+func cloneTask(task *models.Task) *models.Task {
+	clone := *task
+	clone.Status = cloneStatus(task.Status)
+	clone.StatusHistory = cloneSlice(task.StatusHistory, cloneStatus)
+	clone.Artifacts = cloneSlice(task.Artifacts, func(artifact models.Artifact) models.Artifact {
+		artifact.Parts = cloneParts(artifact.Parts)
+		artifact.Metadata = maps.Clone(artifact.Metadata)
+		return artifact
+	})
+	clone.History = cloneSlice(task.History, func(message models.Message) models.Message { return *cloneMessage(&message) })
+	clone.Metadata = maps.Clone(task.Metadata)
+	return &clone
+}
+
+// cloneStatus copies a status and the message it carries
+func cloneStatus(status models.TaskStatus) models.TaskStatus {
+	status.Message = cloneMessage(status.Message)
+	return status
+}
+
+// cloneMessage copies a message and its parts; nil stays nil
+func cloneMessage(message *models.Message) *models.Message {
+	if message == nil {
+		return nil
+	}
+	clone := *message
+	clone.Parts = cloneParts(message.Parts)
+	return &clone
+}
+
+// cloneParts copies parts along with their data and metadata maps
+func cloneParts(parts []models.Part) []models.Part {
+	return cloneSlice(parts, func(part models.Part) models.Part {
+		part.Data = maps.Clone(part.Data)
+		part.Metadata = maps.Clone(part.Metadata)
+		return part
+	})
+}
+
+// cloneSlice returns a new slice holding clone of each item; nil stays nil
+func cloneSlice[T any](items []T, clone func(T) T) []T {
+	if items == nil {
+		return nil
+	}
+	cloned := make([]T, len(items))
+	for i, item := range items {
+		cloned[i] = clone(item)
+	}
+	return cloned
+}
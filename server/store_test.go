@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"a2a/models"
+)
+
+func TestMemoryTaskStore(t *testing.T) {
+	store := NewMemoryTaskStore()
+
+	if _, err := store.Get("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("Expected ErrTaskNotFound, got %v", err)
+	}
+
+	task := &models.Task{ID: "task-1", Status: models.TaskStatus{State: models.TaskStateWorking}}
+	if err := store.Put(task); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// Mutating the caller's copy must not leak into the store
+	task.Status.State = models.TaskStateFailed
+	got, err := store.Get("task-1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Status.State != models.TaskStateWorking {
+		t.Errorf("Expected stored state %s, got %s", models.TaskStateWorking, got.Status.State)
+	}
+
+	_ = store.AppendHistory("task-1", &models.Message{Role: "user"})
+	_ = store.AppendHistory("task-1", &models.Message{Role: "agent"})
+	history, _ := store.History("task-1")
+	if len(history) != 2 || history[0].Role != "user" || history[1].Role != "agent" {
+		t.Errorf("Expected user then agent history, got %v", history)
+	}
+
+	_ = store.Put(&models.Task{ID: "task-0"})
+	tasks, _ := store.List()
+	if len(tasks) != 2 || tasks[0].ID != "task-0" || tasks[1].ID != "task-1" {
+		t.Errorf("Expected tasks ordered by ID, got %v", tasks)
+	}
+
+	if err := store.Delete("task-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get("task-1"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound after delete, got %v", err)
+	}
+	if history, _ := store.History("task-1"); len(history) != 0 {
+		t.Errorf("Expected history to be deleted, got %v", history)
+	}
+}
+
+func TestMemoryTaskStore_DeepCopies(t *testing.T) {
+	store := NewMemoryTaskStore()
+	parts := make([]models.Part, 1, 4)
+	parts[0] = models.Part{Text: stringPtr("Hel")}
+	task := &models.Task{
+		ID:        "task-1",
+		Artifacts: []models.Artifact{{Parts: parts}},
+		History:   []models.Message{{Role: "user", Parts: []models.Part{{Text: stringPtr("hi")}}}},
+	}
+	if err := store.Put(task); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// Extending an artifact in place, as streamed chunks do, must not reach the stored task
+	applyArtifact(task, models.Artifact{Parts: []models.Part{{Text: stringPtr("lo")}}, Append: boolPtr(true)})
+	task.History[0].Parts[0] = models.Part{Text: stringPtr("changed")}
+
+	got, err := store.Get("task-1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(got.Artifacts[0].Parts) != 1 || *got.History[0].Parts[0].Text != "hi" {
+		t.Fatalf("Expected the stored task to be unchanged, got %+v", got)
+	}
+
+	// Nor may changes to what Get returned
+	got.Artifacts[0].Parts[0] = models.Part{Text: stringPtr("changed")}
+	again, _ := store.Get("task-1")
+	if *again.Artifacts[0].Parts[0].Text != "Hel" {
+		t.Errorf("Expected the stored part to be unchanged, got %q", *again.Artifacts[0].Parts[0].Text)
+	}
+
+	message := &models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("hello")}}}
+	_ = store.AppendHistory("task-1", message)
+	message.Parts[0] = models.Part{Text: stringPtr("changed")}
+	history, _ := store.History("task-1")
+	if *history[0].Parts[0].Text != "hello" {
+		t.Errorf("Expected the stored message to be unchanged, got %q", *history[0].Parts[0].Text)
+	}
+}
+
+func TestA2AServer_WithTaskStore(t *testing.T) {
+	store := NewMemoryTaskStore()
+	server := NewA2AServer(mockAgentCard, mockTaskHandler, WithTaskStore(store))
+
+	doRPC(t, server, "message/send", models.TaskSendParams{
+		ID:      "test-task-1",
+		Message: models.Message{Role: "user", Parts: []models.Part{{Text: stringPtr("Hello")}}},
+	})
+
+	task, err := store.Get("test-task-1")
+	if err != nil {
+		t.Fatalf("Expected task in injected store, got %v", err)
+	}
+	if task.Status.State != models.TaskStateCompleted {
+		t.Errorf("Expected task state %s, got %s", models.TaskStateCompleted, task.Status.State)
+	}
+}
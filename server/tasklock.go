@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// taskLock serializes handler runs for one task. Waiters are served in arrival order,
+// so messages sent to the same task are processed in the order they came in.
+type taskLock struct {
+	held    bool
+	waiters []chan struct{}
+}
+
+// taskLocks hands out per-task locks and forgets them once nobody holds or waits for them
+type taskLocks struct {
+	locks map[string]*taskLock
+	mu    sync.Mutex
+	// freed, when set, is called after a task's lock is released with nobody waiting for it
+	freed func()
+}
+
+func newTaskLocks() *taskLocks {
+	return &taskLocks{locks: make(map[string]*taskLock)}
+}
+
+// lock waits until the caller holds the lock for taskID or ctx is done. On success it returns
+// the function that releases the lock.
+func (l *taskLocks) lock(ctx context.Context, taskID string) (func(), error) {
+	l.mu.Lock()
+	lock, exists := l.locks[taskID]
+	if !exists {
+		lock = &taskLock{}
+		l.locks[taskID] = lock
+	}
+	if !lock.held {
+		lock.held = true
+		l.mu.Unlock()
+		return func() { l.unlock(taskID) }, nil
+	}
+	ready := make(chan struct{})
+	lock.waiters = append(lock.waiters, ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return func() { l.unlock(taskID) }, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		for i, waiter := range lock.waiters {
+			if waiter == ready {
+				lock.waiters = append(lock.waiters[:i], lock.waiters[i+1:]...)
+				l.mu.Unlock()
+				return nil, ctx.Err()
+			}
+		}
+		l.mu.Unlock()
+		// The lock was handed over just as ctx finished; pass it on
+		l.unlock(taskID)
+		return nil, ctx.Err()
+	}
+}
+
+// tryLock takes the lock for taskID if nobody holds it, without waiting
+func (l *taskLocks) tryLock(taskID string) (func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, exists := l.locks[taskID]; exists {
+		return nil, false
+	}
+	l.locks[taskID] = &taskLock{held: true}
+	return func() { l.unlock(taskID) }, true
+}
+
+func (l *taskLocks) unlock(taskID string) {
+	l.mu.Lock()
+	l.release(taskID, l.locks[taskID])
+	_, stillHeld := l.locks[taskID]
+	l.mu.Unlock()
+
+	if !stillHeld && l.freed != nil {
+		l.freed()
+	}
+}
+
+// release hands the lock to the next waiter, or drops it when there is none.
+// Must be called with l.mu held.
+func (l *taskLocks) release(taskID string, lock *taskLock) {
+	if len(lock.waiters) > 0 {
+		next := lock.waiters[0]
+		lock.waiters = lock.waiters[1:]
+		close(next)
+		return
+	}
+	lock.held = false
+	delete(l.locks, taskID)
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"a2a/models"
+)
+
+func TestA2AServer_GetStaysResponsiveWhileHandlerRuns(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		if *message.Parts[0].Text == "slow" {
+			close(started)
+			<-release
+		}
+		task.Status.State = models.TaskStateCompleted
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+	defer close(release)
+
+	go server.ServeHTTP(httptest.NewRecorder(), newRPCRequest("message/send", sendParams("slow-task", "slow")))
+	waitFor(t, started, "slow handler to start")
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+
+		// The running task itself can be inspected
+		response := doRPC(t, server, "tasks/get", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "slow-task"}})
+		var task models.Task
+		decodeResult(t, response.Result, &task)
+		if task.Status.State != models.TaskStateWorking {
+			t.Errorf("Expected running task to be %s, got %s", models.TaskStateWorking, task.Status.State)
+		}
+
+		// Other tasks are not held up by it
+		response = doRPC(t, server, "message/send", sendParams("fast-task", "fast"))
+		if response.Error != nil {
+			t.Errorf("Expected no error, got %v", response.Error)
+		}
+		response = doRPC(t, server, "tasks/get", models.TaskQueryParams{TaskIDParams: models.TaskIDParams{ID: "fast-task"}})
+		decodeResult(t, response.Result, &task)
+		if task.Status.State != models.TaskStateCompleted {
+			t.Errorf("Expected other task to be %s, got %s", models.TaskStateCompleted, task.Status.State)
+		}
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("tasks/get and message/send blocked behind a running handler")
+	}
+}
+
+func TestA2AServer_SameTaskMessagesAreSerialized(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	running := 0
+	handler := func(ctx context.Context, task *models.Task, message *models.Message, update func(any)) (*models.Task, error) {
+		mu.Lock()
+		running++
+		if running > 1 {
+			t.Error("Two handlers ran concurrently for the same task")
+		}
+		order = append(order, *message.Parts[0].Text)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		task.Status.State = models.TaskStateInputRequired
+		return task, nil
+	}
+	server := NewA2AServer(mockAgentCard, handler)
+
+	var wg sync.WaitGroup
+	for _, text := range []string{"first", "second", "third"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.ServeHTTP(httptest.NewRecorder(), newRPCRequest("message/send", sendParams("test-task-1", text)))
+		}()
+		// Stagger the arrivals so the expected order is well defined
+		time.Sleep(2 * time.Millisecond)
+	}
+	wg.Wait()
+
+	if got := strings.Join(order, ","); got != "first,second,third" {
+		t.Errorf("Expected messages handled in arrival order, got %s", got)
+	}
+}
+
+func TestTaskLocks_WaiterGivesUp(t *testing.T) {
+	locks := newTaskLocks()
+
+	unlock, err := locks.lock(context.Background(), "task-1")
+	if err != nil {
+		t.Fatalf("Expected lock, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := locks.lock(ctx, "task-1"); err == nil {
+		t.Fatal("Expected waiting for a held lock to time out")
+	}
+
+	unlock()
+	unlock, err = locks.lock(context.Background(), "task-1")
+	if err != nil {
+		t.Fatalf("Expected lock after release, got %v", err)
+	}
+	unlock()
+	if len(locks.locks) != 0 {
+		t.Errorf("Expected released locks to be forgotten, got %d", len(locks.locks))
+	}
+}